@@ -0,0 +1,83 @@
+package gomigration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gocraft/dbr"
+)
+
+// ChecksumMismatch is an executed migration whose content changed since it was applied.
+type ChecksumMismatch struct {
+	Name string
+	// Path is the up file of migrations loaded from sql files.
+	Path    string
+	Stored  string
+	Current string
+}
+
+// ChecksumError lists the executed migrations that were edited after they were applied.
+type ChecksumError struct {
+	Mismatches []ChecksumMismatch
+}
+
+func (e *ChecksumError) Error() string {
+	problems := make([]string, 0, len(e.Mismatches))
+	for _, m := range e.Mismatches {
+		source := m.Name
+		if "" != m.Path {
+			source = m.Path
+		}
+		problems = append(problems, fmt.Sprintf("%s changed, checksum %s was applied but is now %s", source, m.Stored, m.Current))
+	}
+	return "migrations were edited after they were applied: " + strings.Join(problems, "; ")
+}
+
+// VerifyChecksums compares the Checksum of every executed migration with the one stored when it was applied and returns
+// a *ChecksumError listing the edited ones. Migrations without checksum in the code or in the meta table are skipped.
+// With UpdateChecksums edited migrations get their stored checksum replaced and are only reported through Logger.
+func (mM MigrationManager) VerifyChecksums(session *dbr.Session, migrations []Migration) error {
+	executed, err := mM.ListExecuted(session)
+	if nil != err {
+		return err
+	}
+	stored := make(map[string]string, len(executed))
+	for _, e := range executed {
+		if e.Checksum.Valid && "" != e.Checksum.String {
+			stored[e.Name] = e.Checksum.String
+		}
+	}
+	checksumError := &ChecksumError{}
+	for _, migration := range migrations {
+		checksum, ok := stored[mM.normalize(migration.Name)]
+		if !ok || "" == migration.Checksum || checksum == migration.Checksum {
+			continue
+		}
+		checksumError.Mismatches = append(checksumError.Mismatches, ChecksumMismatch{
+			Name:    migration.Name,
+			Path:    migration.upFile,
+			Stored:  checksum,
+			Current: migration.Checksum,
+		})
+	}
+	if 0 == len(checksumError.Mismatches) {
+		return nil
+	}
+	if !mM.UpdateChecksums {
+		return checksumError
+	}
+	for _, m := range checksumError.Mismatches {
+		builder := session.Update(mM.table()).Set("checksum", m.Current).Where(nameEquals(mM.Dialect), mM.normalize(m.Name))
+		if mM.SoftDelete {
+			builder = builder.Where("rolled_back_at IS NULL")
+		}
+		if "" != mM.PartitionColumn {
+			builder = builder.Where(mM.partitionEquals(), mM.PartitionValue)
+		}
+		if _, err = builder.Exec(); nil != err {
+			return err
+		}
+	}
+	mM.warn("%s, stored the new checksums", checksumError)
+	return nil
+}
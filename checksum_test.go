@@ -0,0 +1,51 @@
+package gomigration
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyChecksumsBeforeRun(t *testing.T) {
+	mM := testManager(t)
+	mM.VerifyChecksumsBeforeRun = true
+	dir := writeFiles(t, map[string]string{
+		"001_users.up.sql":   "CREATE TABLE users (id INTEGER);",
+		"001_users.down.sql": "DROP TABLE users;",
+	})
+	migrations, err := LoadFromDir(dir)
+	if nil != err {
+		t.Fatal(err)
+	}
+	mM.MigrationRunner(migrations)
+	files := map[string]string{
+		"001_users.up.sql":    "CREATE TABLE users (id INTEGER, name TEXT);",
+		"002_orders.up.sql":   "CREATE TABLE orders (id INTEGER);",
+		"002_orders.down.sql": "DROP TABLE orders;",
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); nil != err {
+			t.Fatal(err)
+		}
+	}
+	if migrations, err = LoadFromDir(dir); nil != err {
+		t.Fatal(err)
+	}
+	var checksumErr *ChecksumError
+	if err = mM.MigrationRunnerContext(context.Background(), migrations); !errors.As(err, &checksumErr) {
+		t.Fatalf("expected a *ChecksumError, got %v", err)
+	}
+	session := mM.Connection.NewSession(nil)
+	if mM.CheckIfExecuted(session, migrations[1]) {
+		t.Fatal("expected no migration to be applied after the edit")
+	}
+	mM.UpdateChecksums = true
+	if err = mM.MigrationRunnerContext(context.Background(), migrations); nil != err {
+		t.Fatal(err)
+	}
+	if !mM.CheckIfExecuted(session, migrations[1]) {
+		t.Error("expected the pending migration to be applied with UpdateChecksums")
+	}
+}
@@ -0,0 +1,101 @@
+package gomigration
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/gocraft/dbr"
+)
+
+// ChunkFunc processes the rows whose keys are between from and to, both included, within transaction.
+type ChunkFunc func(transaction *dbr.Tx, from, to int64) error
+
+// ChunksTableName returns the name of the table the progress of Chunked is recorded in.
+func (mM MigrationManager) ChunksTableName() string {
+	return mM.table() + "Chunks"
+}
+
+// Chunked runs a backfill of table in chunks of batchSize keys of its integer primary key column key, calling fn for
+// each range in its own short transaction instead of locking millions of rows in a single one. The transactions are
+// read committed unless the MigrationManager has an Isolation, SQLite keeps its default. The last key done is stored
+// under name in ChunksTableName within the transaction of each chunk, so a backfill that was interrupted resumes after
+// the last committed chunk when Chunked is called again with the same name. The progress is deleted once all chunks are
+// done. fn is not called at all for an empty table. It is meant to be called from Up with a session of the Connection,
+// not the transaction of the migration: the chunks are committed on their own and stay even if the migration fails, so
+// fn has to be idempotent. Rows inserted with keys above the maximum found at the start are not processed. Migrations
+// calling it should set OutsideTransaction.
+func (mM MigrationManager) Chunked(session *dbr.Session, name, table, key string, batchSize int64, fn ChunkFunc) error {
+	if 1 > batchSize {
+		return errors.New(fmt.Sprintf("batch size of chunked backfill \"%s\" must be positive", name))
+	}
+	transaction, err := session.Begin()
+	if nil != err {
+		return err
+	}
+	if _, err = transaction.Exec(createChunksTableSQL(mM.ChunksTableName())); nil != err {
+		transaction.Rollback()
+		return err
+	}
+	if err = transaction.Commit(); nil != err {
+		return err
+	}
+	quotedKey := quoteIdentifier(mM.Dialect, key)
+	bounds := func(aggregate string) (dbr.NullInt64, error) {
+		var bound dbr.NullInt64
+		err := session.Select(aggregate + "(" + quotedKey + ")").From(quoteIdentifier(mM.Dialect, table)).LoadValue(&bound)
+		return bound, err
+	}
+	first, err := bounds("MIN")
+	if nil != err {
+		return err
+	}
+	if !first.Valid {
+		// the table is empty, there is nothing to process
+		_, err = session.DeleteFrom(mM.ChunksTableName()).Where("name = ?", name).Exec()
+		return err
+	}
+	highest, err := bounds("MAX")
+	if nil != err {
+		return err
+	}
+	from, last := first.Int64, highest.Int64
+	done, err := session.Select("last_key").From(mM.ChunksTableName()).Where("name = ?", name).ReturnInt64s()
+	if nil != err {
+		return err
+	}
+	if 0 < len(done) && done[0] >= from {
+		from = done[0] + 1
+	}
+	isolation := mM.Isolation
+	if sql.LevelDefault == isolation && SQLite != mM.Dialect {
+		isolation = sql.LevelReadCommitted
+	}
+	for ; from <= last; from += batchSize {
+		to := from + batchSize - 1
+		if err = mM.chunk(session, isolation, name, from, to, fn); nil != err {
+			return fmt.Errorf("chunk %d to %d of backfill \"%s\": %w", from, to, name, err)
+		}
+	}
+	_, err = session.DeleteFrom(mM.ChunksTableName()).Where("name = ?", name).Exec()
+	return err
+}
+
+// chunk runs fn for the keys from to to and records to as the last key done in one transaction.
+func (mM MigrationManager) chunk(session *dbr.Session, isolation sql.IsolationLevel, name string, from, to int64, fn ChunkFunc) error {
+	transaction, _, err := mM.begin(session, isolation)
+	if nil != err {
+		return err
+	}
+	defer transaction.RollbackUnlessCommitted()
+	if err = fn(transaction, from, to); nil != err {
+		return err
+	}
+	if _, err = transaction.DeleteFrom(mM.ChunksTableName()).Where("name = ?", name).Exec(); nil != err {
+		return err
+	}
+	if _, err = transaction.InsertInto(mM.ChunksTableName()).Pair("name", name).Pair("last_key", to).Exec(); nil != err {
+		return err
+	}
+	return transaction.Commit()
+}
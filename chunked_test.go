@@ -0,0 +1,49 @@
+package gomigration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gocraft/dbr"
+)
+
+func TestChunkedSkipsEmptyTable(t *testing.T) {
+	mM := testManager(t)
+	execute(t, mM.Connection, "CREATE TABLE users (id INTEGER PRIMARY KEY)")
+	called := false
+	err := mM.Chunked(mM.Connection.NewSession(nil), "empty", "users", "id", 10, func(*dbr.Tx, int64, int64) error {
+		called = true
+		return nil
+	})
+	if nil != err || called {
+		t.Errorf("expected no chunk for an empty table, got %v, called %v", err, called)
+	}
+}
+
+func TestChunkedResumes(t *testing.T) {
+	mM := testManager(t)
+	execute(t, mM.Connection, "CREATE TABLE users (id INTEGER PRIMARY KEY)")
+	execute(t, mM.Connection, "INSERT INTO users (id) VALUES (1), (2), (3), (4), (5)")
+	session := mM.Connection.NewSession(nil)
+	var chunks [][2]int64
+	failing := func(_ *dbr.Tx, from, to int64) error {
+		if 3 == from {
+			return errors.New("interrupted")
+		}
+		chunks = append(chunks, [2]int64{from, to})
+		return nil
+	}
+	if err := mM.Chunked(session, "backfill", "users", "id", 2, failing); nil == err {
+		t.Fatal("expected the second chunk to fail")
+	}
+	err := mM.Chunked(session, "backfill", "users", "id", 2, func(_ *dbr.Tx, from, to int64) error {
+		chunks = append(chunks, [2]int64{from, to})
+		return nil
+	})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 3 != len(chunks) || ([2]int64{1, 2}) != chunks[0] || ([2]int64{3, 4}) != chunks[1] || ([2]int64{5, 6}) != chunks[2] {
+		t.Errorf("expected the chunks 1-2, 3-4 and 5-6, got %v", chunks)
+	}
+}
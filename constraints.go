@@ -0,0 +1,157 @@
+package gomigration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gocraft/dbr"
+)
+
+// ConstraintError lists the constraint problems found by ValidateConstraints.
+type ConstraintError struct {
+	Problems []string
+}
+
+func (e *ConstraintError) Error() string {
+	return "constraints are not valid: " + strings.Join(e.Problems, "; ")
+}
+
+// foreignKeyColumn is a column of a foreign key as described by information_schema.KEY_COLUMN_USAGE.
+type foreignKeyColumn struct {
+	Name       string `db:"name"`
+	Table      string `db:"child"`
+	Column     string `db:"col"`
+	Parent     string `db:"parent"`
+	Referenced string `db:"ref"`
+}
+
+// ValidateConstraints checks that foreign keys are enforced and hold after migrations ran, e.g. to catch a migration
+// that disabled foreign key checks and forgot to enable them again, and returns a *ConstraintError naming every
+// problem. It is not run automatically. MySQL is checked for FOREIGN_KEY_CHECKS being off and for rows of foreign keys
+// without parent row, Postgres for constraints that are NOT VALID, disabled triggers, which include the ones of
+// foreign keys, and session_replication_role being replica, SQLite for foreign_keys being off and for the violations
+// reported by foreign_key_check. Settings are per connection, so only the one of a connection of the pool of session
+// is seen.
+func (mM MigrationManager) ValidateConstraints(session *dbr.Session) error {
+	var problems []string
+	var err error
+	switch mM.Dialect {
+	case Postgres:
+		problems, err = postgresConstraintProblems(session)
+	case SQLite:
+		problems, err = sqliteConstraintProblems(session)
+	default:
+		problems, err = mysqlConstraintProblems(session)
+	}
+	if nil != err {
+		return err
+	}
+	if 0 < len(problems) {
+		return &ConstraintError{Problems: problems}
+	}
+	return nil
+}
+
+func mysqlConstraintProblems(session *dbr.Session) ([]string, error) {
+	var problems []string
+	checks, err := session.SelectBySql("SELECT @@FOREIGN_KEY_CHECKS").ReturnInt64()
+	if nil != err {
+		return nil, err
+	}
+	if 1 != checks {
+		problems = append(problems, "FOREIGN_KEY_CHECKS is disabled")
+	}
+	var columns []foreignKeyColumn
+	_, err = session.SelectBySql(`SELECT CONSTRAINT_NAME AS name, TABLE_NAME AS child, COLUMN_NAME AS col,
+	REFERENCED_TABLE_NAME AS parent, REFERENCED_COLUMN_NAME AS ref
+FROM information_schema.KEY_COLUMN_USAGE
+WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL
+ORDER BY TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION`).LoadStructs(&columns)
+	if nil != err {
+		return nil, err
+	}
+	for start := 0; start < len(columns); {
+		end := start + 1
+		for end < len(columns) && columns[end].Table == columns[start].Table && columns[end].Name == columns[start].Name {
+			end++
+		}
+		orphans, err := session.SelectBySql(orphansSQL(columns[start:end])).ReturnInt64()
+		if nil != err {
+			return nil, err
+		}
+		if 0 < orphans {
+			problems = append(problems, fmt.Sprintf("%d rows of table %s violate foreign key %s referencing %s",
+				orphans, columns[start].Table, columns[start].Name, columns[start].Parent))
+		}
+		start = end
+	}
+	return problems, nil
+}
+
+// orphansSQL returns the query counting the rows violating the foreign key made of columns. Like MySQL itself it
+// skips rows with a NULL in any of the columns.
+func orphansSQL(columns []foreignKeyColumn) string {
+	var notNull, matches []string
+	for _, column := range columns {
+		child := "c." + quoteIdentifier(MySQL, column.Column)
+		notNull = append(notNull, child+" IS NOT NULL")
+		matches = append(matches, "p."+quoteIdentifier(MySQL, column.Referenced)+" = "+child)
+	}
+	return "SELECT COUNT(*) FROM " + quoteIdentifier(MySQL, columns[0].Table) + " c WHERE " + strings.Join(notNull, " AND ") +
+		" AND NOT EXISTS (SELECT 1 FROM " + quoteIdentifier(MySQL, columns[0].Parent) + " p WHERE " + strings.Join(matches, " AND ") + ")"
+}
+
+func postgresConstraintProblems(session *dbr.Session) ([]string, error) {
+	var problems []string
+	role, err := session.SelectBySql("SELECT current_setting('session_replication_role')").ReturnString()
+	if nil != err {
+		return nil, err
+	}
+	if "replica" == role {
+		problems = append(problems, "session_replication_role is replica, foreign keys are not enforced")
+	}
+	var objects []struct {
+		Name  string `db:"name"`
+		Table string `db:"tbl"`
+	}
+	_, err = session.SelectBySql(`SELECT conname AS name, conrelid::regclass::text AS tbl FROM pg_constraint
+WHERE NOT convalidated ORDER BY 2, 1`).LoadStructs(&objects)
+	if nil != err {
+		return nil, err
+	}
+	for _, object := range objects {
+		problems = append(problems, fmt.Sprintf("constraint %s of table %s is not validated", object.Name, object.Table))
+	}
+	objects = nil
+	_, err = session.SelectBySql(`SELECT tgname AS name, tgrelid::regclass::text AS tbl FROM pg_trigger
+WHERE 'D' = tgenabled ORDER BY 2, 1`).LoadStructs(&objects)
+	if nil != err {
+		return nil, err
+	}
+	for _, object := range objects {
+		problems = append(problems, fmt.Sprintf("trigger %s of table %s is disabled", object.Name, object.Table))
+	}
+	return problems, nil
+}
+
+func sqliteConstraintProblems(session *dbr.Session) ([]string, error) {
+	var problems []string
+	enabled, err := session.SelectBySql("PRAGMA foreign_keys").ReturnInt64()
+	if nil != err {
+		return nil, err
+	}
+	if 1 != enabled {
+		problems = append(problems, "foreign_keys is disabled")
+	}
+	var violations []struct {
+		Table  string `db:"table"`
+		Parent string `db:"parent"`
+	}
+	if _, err = session.SelectBySql("PRAGMA foreign_key_check").LoadStructs(&violations); nil != err {
+		return nil, err
+	}
+	for _, violation := range violations {
+		problems = append(problems, fmt.Sprintf("a row of table %s violates a foreign key referencing %s", violation.Table, violation.Parent))
+	}
+	return problems, nil
+}
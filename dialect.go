@@ -0,0 +1,156 @@
+package gomigration
+
+import (
+	"strings"
+)
+
+// Dialect selects the sql flavour used for the statements the MigrationManager generates itself.
+type Dialect int
+
+const (
+	// MySQL is the default dialect.
+	MySQL Dialect = iota
+	Postgres
+	SQLite
+)
+
+// quoteIdentifier quotes a table or column name for dialect.
+func quoteIdentifier(dialect Dialect, name string) string {
+	if MySQL == dialect {
+		return "`" + strings.Replace(name, "`", "``", -1) + "`"
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// nameEquals returns the condition matching a migration by name, comparing names byte by byte regardless of the
+// collation of the name column.
+func nameEquals(dialect Dialect) string {
+	if MySQL == dialect {
+		return "name = BINARY ?"
+	}
+	return "name = ?"
+}
+
+// createTableSQL returns the statement creating the meta table called tableName unless it exists.
+// Like the queries built by dbr, the statements creating the tables of the MigrationManager use their names unquoted,
+// so these have to be plain identifiers, which Postgres folds to lower case.
+// The name column is case-sensitive in every dialect. MySQL tables created before the name column was declared as
+// utf8mb4_bin keep their collation and can be converted with:
+//
+//	ALTER TABLE `dbMigrations` MODIFY name VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin
+func createTableSQL(tableName string, dialect Dialect, keys KeyStrategy) string {
+	switch dialect {
+	case Postgres:
+		return "CREATE TABLE IF NOT EXISTS " + tableName + ` (
+	` + idColumn(dialect, keys) + `,
+	name VARCHAR(255) COLLATE "C",
+	execution TIMESTAMP,
+	rolled_back_at TIMESTAMP NULL,
+	meta TEXT NULL,
+	checksum VARCHAR(64) NULL,
+	version VARCHAR(255) NULL,
+	deployment_id VARCHAR(255) NULL,
+	duration_ms BIGINT NULL,
+	PRIMARY KEY (id)
+)`
+	case SQLite:
+		return "CREATE TABLE IF NOT EXISTS " + tableName + ` (
+	` + idColumn(dialect, keys) + `,
+	name VARCHAR(255) COLLATE BINARY,
+	execution DATETIME,
+	rolled_back_at DATETIME NULL,
+	meta TEXT NULL,
+	checksum VARCHAR(64) NULL,
+	version VARCHAR(255) NULL,
+	deployment_id VARCHAR(255) NULL,
+	duration_ms BIGINT NULL
+)`
+	}
+	return "CREATE TABLE IF NOT EXISTS " + tableName + ` (
+	` + idColumn(dialect, keys) + `,
+	name VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin,
+	execution DATETIME,
+	rolled_back_at DATETIME NULL,
+	meta TEXT NULL,
+	checksum VARCHAR(64) NULL,
+	version VARCHAR(255) NULL,
+	deployment_id VARCHAR(255) NULL,
+	duration_ms BIGINT NULL,
+	PRIMARY KEY (id)
+)`
+}
+
+// idColumn returns the definition of the id column of the meta table.
+func idColumn(dialect Dialect, keys KeyStrategy) string {
+	switch dialect {
+	case Postgres:
+		if KeyUUID == keys {
+			return "id UUID"
+		}
+		return "id SERIAL"
+	case SQLite:
+		if KeyUUID == keys {
+			return "id CHAR(36) PRIMARY KEY"
+		}
+		return "id INTEGER PRIMARY KEY AUTOINCREMENT"
+	}
+	if KeyUUID == keys {
+		return "id CHAR(36) NOT NULL"
+	}
+	return "id INT NOT NULL AUTO_INCREMENT"
+}
+
+// createFailuresTableSQL returns the statement creating the table failed attempts are recorded in unless it exists.
+func createFailuresTableSQL(tableName string, dialect Dialect) string {
+	switch dialect {
+	case Postgres:
+		return "CREATE TABLE IF NOT EXISTS " + tableName + ` (
+	id SERIAL,
+	name VARCHAR(255) COLLATE "C",
+	error TEXT,
+	execution TIMESTAMP,
+	PRIMARY KEY (id)
+)`
+	case SQLite:
+		return "CREATE TABLE IF NOT EXISTS " + tableName + ` (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(255) COLLATE BINARY,
+	error TEXT,
+	execution DATETIME
+)`
+	}
+	return "CREATE TABLE IF NOT EXISTS " + tableName + ` (
+	id INT NOT NULL AUTO_INCREMENT,
+	name VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin,
+	error TEXT,
+	execution DATETIME,
+	PRIMARY KEY (id)
+)`
+}
+
+// foreignKeyChecksSQL returns the statement enabling or disabling foreign key checks for the current connection, or an
+// empty string for SQLite which can not change them within a transaction.
+func foreignKeyChecksSQL(dialect Dialect, enabled bool) string {
+	switch dialect {
+	case Postgres:
+		if enabled {
+			return "SET session_replication_role = DEFAULT"
+		}
+		return "SET session_replication_role = replica"
+	case SQLite:
+		return ""
+	}
+	if enabled {
+		return "SET FOREIGN_KEY_CHECKS = 1"
+	}
+	return "SET FOREIGN_KEY_CHECKS = 0"
+}
+
+// createChunksTableSQL returns the statement creating the table the progress of Chunked is recorded in unless it exists.
+func createChunksTableSQL(tableName string) string {
+	return "CREATE TABLE IF NOT EXISTS " + tableName + ` (
+	name VARCHAR(255) NOT NULL,
+	last_key BIGINT NOT NULL,
+	PRIMARY KEY (name)
+)`
+}
@@ -0,0 +1,71 @@
+package gomigration
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCreateTableSQL(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		keys    KeyStrategy
+		parts   []string
+	}{
+		{MySQL, KeyAutoIncrement, []string{"id INT NOT NULL AUTO_INCREMENT", "COLLATE utf8mb4_bin", "execution DATETIME", "PRIMARY KEY (id)"}},
+		{Postgres, KeyAutoIncrement, []string{"id SERIAL", `COLLATE "C"`, "execution TIMESTAMP", "PRIMARY KEY (id)"}},
+		{SQLite, KeyAutoIncrement, []string{"id INTEGER PRIMARY KEY AUTOINCREMENT", "COLLATE BINARY", "execution DATETIME"}},
+		{MySQL, KeyUUID, []string{"id CHAR(36) NOT NULL"}},
+		{Postgres, KeyUUID, []string{"id UUID"}},
+		{SQLite, KeyUUID, []string{"id CHAR(36) PRIMARY KEY"}},
+	}
+	for _, test := range tests {
+		statement := createTableSQL("dbMigrations", test.dialect, test.keys)
+		if !strings.HasPrefix(statement, "CREATE TABLE IF NOT EXISTS dbMigrations (") {
+			t.Errorf("dialect %d: expected the unquoted table name, got %s", test.dialect, statement)
+		}
+		for _, part := range append(test.parts, "rolled_back_at", "meta TEXT NULL", "checksum VARCHAR(64) NULL", "version", "deployment_id", "duration_ms") {
+			if !strings.Contains(statement, part) {
+				t.Errorf("dialect %d: expected %q in %s", test.dialect, part, statement)
+			}
+		}
+	}
+}
+
+func TestCreateTableSQLIsDeterministic(t *testing.T) {
+	for _, dialect := range []Dialect{MySQL, Postgres, SQLite} {
+		if createTableSQL("meta", dialect, KeyAutoIncrement) != createTableSQL("meta", dialect, KeyAutoIncrement) {
+			t.Errorf("dialect %d: expected the same statement twice", dialect)
+		}
+	}
+}
+
+func TestWithDialectAppliesBeforeInit(t *testing.T) {
+	mM := NewMigrationManager(testConnection(t), WithDialect(SQLite), WithTableName("meta"))
+	if "meta" != mM.TableName() {
+		t.Fatalf("expected the meta table \"meta\", got %s", mM.TableName())
+	}
+	initialized, err := mM.IsInitialized(mM.Connection.NewSession(nil))
+	if nil != err || !initialized {
+		t.Fatalf("expected the meta table to exist, got %v, %v", initialized, err)
+	}
+}
+
+func TestWithCreateTableSQL(t *testing.T) {
+	connection := testConnection(t)
+	custom := "CREATE TABLE IF NOT EXISTS dbMigrations (id INTEGER PRIMARY KEY, name VARCHAR(255), execution DATETIME, author TEXT)"
+	mM := NewMigrationManager(connection, WithDialect(SQLite), WithCreateTableSQL(custom))
+	var columns []string
+	if _, err := mM.Connection.NewSession(nil).SelectBySql("SELECT name FROM pragma_table_info('dbMigrations')").LoadValues(&columns); nil != err {
+		t.Fatal(err)
+	}
+	if 4 != len(columns) || "author" != columns[3] {
+		t.Errorf("expected the columns of the custom statement, got %v", columns)
+	}
+	if err := initError(testConnection(t), WithDialect(SQLite), WithCreateTableSQL("CREATE TABLE other (id INTEGER)")); nil == err {
+		t.Error("expected a statement not mentioning the meta table to be rejected")
+	}
+	missing := "CREATE TABLE IF NOT EXISTS dbMigrations (id INTEGER PRIMARY KEY, name VARCHAR(255))"
+	if err := initError(testConnection(t), WithDialect(SQLite), WithCreateTableSQL(missing), WithSchemaCheck(SchemaCheckOff)); nil == err {
+		t.Error("expected a table without execution column to be rejected")
+	}
+}
@@ -0,0 +1,118 @@
+package gomigration
+
+import (
+	"sort"
+
+	"github.com/gocraft/dbr"
+)
+
+// SetDiff describes how a list of migrations a differs from a list b, see DiffSets.
+type SetDiff struct {
+	// Added are the names only in a, Removed those only in b.
+	Added, Removed []string
+	// Reordered are names in both lists whose order relative to the others differs.
+	Reordered []string
+	// Interleaved are added names that a lists before one of the names both lists share.
+	Interleaved []string
+}
+
+// Compatible reports if b is a prefix of a, i.e. a only appends migrations to b.
+func (d SetDiff) Compatible() bool {
+	return 0 == len(d.Removed) && 0 == len(d.Reordered) && 0 == len(d.Interleaved)
+}
+
+// DiffSets compares the migrations a with b without touching a database, e.g. to check before merging two branches that
+// they did not add conflicting migrations.
+func DiffSets(a, b []Migration) SetDiff {
+	positionInB := make(map[string]int, len(b))
+	for i, migration := range b {
+		positionInB[migration.Name] = i
+	}
+	inA := make(map[string]bool, len(a))
+	diff := SetDiff{}
+	common := make([]string, 0, len(a))
+	lastCommon := -1
+	for i, migration := range a {
+		inA[migration.Name] = true
+		if _, ok := positionInB[migration.Name]; ok {
+			common = append(common, migration.Name)
+			lastCommon = i
+		}
+	}
+	for i, migration := range a {
+		if _, ok := positionInB[migration.Name]; !ok {
+			diff.Added = append(diff.Added, migration.Name)
+			if i < lastCommon {
+				diff.Interleaved = append(diff.Interleaved, migration.Name)
+			}
+		}
+	}
+	for _, migration := range b {
+		if !inA[migration.Name] {
+			diff.Removed = append(diff.Removed, migration.Name)
+		}
+	}
+	inOrder := longestOrderedRun(common, positionInB)
+	for _, name := range common {
+		if !inOrder[name] {
+			diff.Reordered = append(diff.Reordered, name)
+		}
+	}
+	return diff
+}
+
+// longestOrderedRun returns the largest subset of names that is in the same order in positions, so the remaining
+// names are the fewest that moved.
+func longestOrderedRun(names []string, positions map[string]int) map[string]bool {
+	// tails[k] is the index into names ending the best run of length k+1 found so far.
+	tails := make([]int, 0, len(names))
+	previous := make([]int, len(names))
+	for i, name := range names {
+		k := sort.Search(len(tails), func(k int) bool { return positions[names[tails[k]]] >= positions[name] })
+		if 0 < k {
+			previous[i] = tails[k-1]
+		} else {
+			previous[i] = -1
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+	run := make(map[string]bool, len(tails))
+	if 0 == len(tails) {
+		return run
+	}
+	for i := tails[len(tails)-1]; 0 <= i; i = previous[i] {
+		run[names[i]] = true
+	}
+	return run
+}
+
+// CompareDatabases returns the names of the migrations executed on only one of two databases, e.g. what promoting
+// staging to production will apply, each sorted by name. Every database is read with its own MigrationManager, so the
+// meta tables may have different names or settings.
+func CompareDatabases(a MigrationManager, sessionA *dbr.Session, b MigrationManager, sessionB *dbr.Session) (onlyA, onlyB []string, err error) {
+	executedA, err := a.ExecutedNames(sessionA)
+	if nil != err {
+		return nil, nil, err
+	}
+	executedB, err := b.ExecutedNames(sessionB)
+	if nil != err {
+		return nil, nil, err
+	}
+	return missingFrom(executedA, executedB), missingFrom(executedB, executedA), nil
+}
+
+// missingFrom returns the names of the sorted list names that are not in the sorted list other.
+func missingFrom(names, other []string) []string {
+	missing := []string{}
+	for _, name := range names {
+		i := sort.SearchStrings(other, name)
+		if i == len(other) || other[i] != name {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
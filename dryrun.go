@@ -0,0 +1,96 @@
+package gomigration
+
+import (
+	"database/sql"
+
+	"github.com/gocraft/dbr"
+)
+
+// SQLPreview is the sql the Up of a migration executes.
+type SQLPreview struct {
+	Name       string
+	Statements []string
+	// Opaque is set for migrations built from Go functions, whose sql can not be known without running them.
+	Opaque bool
+}
+
+// DryRunResult is the outcome of DryRun for a single pending migration.
+type DryRunResult struct {
+	SQLPreview
+	// Err is the error the migration failed with while being applied by the rollback-based dry run.
+	Err error
+	// Skipped is set for migrations the rollback-based dry run did not apply because they have effects the rollback
+	// does not undo, that is migrations with External or OutsideTransaction.
+	Skipped bool
+}
+
+// PreviewSQL returns the statements the Up of every migration executes without needing a database.
+// Only migrations loaded from sql files can be previewed, all others are Opaque.
+func PreviewSQL(migrations []Migration) ([]SQLPreview, error) {
+	previews := make([]SQLPreview, 0, len(migrations))
+	for _, migration := range migrations {
+		preview, err := previewSQL(migration)
+		if nil != err {
+			return nil, err
+		}
+		previews = append(previews, preview)
+	}
+	return previews, nil
+}
+
+func previewSQL(migration Migration) (SQLPreview, error) {
+	if "" == migration.upFile {
+		return SQLPreview{Name: migration.Name, Opaque: true}, nil
+	}
+	statements, err := readStatements(migration.upFile)
+	return SQLPreview{Name: migration.Name, Statements: statements}, err
+}
+
+// DryRun shows what running the pending migrations would do, in two ways:
+// The statements of migrations loaded from sql files are previewed like PreviewSQL does. Additionally all pending
+// migrations are applied in one transaction that is rolled back afterwards, which reveals failing opaque migrations
+// via Err. Applying stops at the first failure. The rollback-based part is skipped with the MySQL Dialect, since it
+// implicitly commits DDL and the dry run would change the schema for real. For the same reason migrations with
+// External or OutsideTransaction are not applied but reported as Skipped, later migrations depending on them may fail.
+func (mM MigrationManager) DryRun(session *dbr.Session, migrations []Migration) ([]DryRunResult, error) {
+	executed, err := mM.executedSet(session)
+	if nil != err {
+		return nil, err
+	}
+	results := make([]DryRunResult, 0, len(migrations))
+	for _, migration := range migrations {
+		if executed[mM.normalize(migration.Name)] {
+			continue
+		}
+		preview, err := previewSQL(migration)
+		if nil != err {
+			return nil, err
+		}
+		results = append(results, DryRunResult{SQLPreview: preview})
+	}
+	if MySQL == mM.Dialect || 0 == len(results) {
+		return results, nil
+	}
+	transaction, _, err := mM.begin(session, sql.LevelDefault)
+	if nil != err {
+		return nil, err
+	}
+	defer transaction.Rollback()
+	i := 0
+	for _, migration := range migrations {
+		if executed[mM.normalize(migration.Name)] {
+			continue
+		}
+		if nil != migration.External || migration.OutsideTransaction {
+			results[i].Skipped = true
+			i++
+			continue
+		}
+		if err := mM.ApplyUp(transaction, migration); nil != err {
+			results[i].Err = err
+			break
+		}
+		i++
+	}
+	return results, nil
+}
@@ -0,0 +1,36 @@
+package gomigration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gocraft/dbr"
+)
+
+func TestDryRunSkipsMigrationsWithOutsideEffects(t *testing.T) {
+	mM := testManager(t)
+	touched := filepath.Join(t.TempDir(), "touched")
+	chunked := false
+	migrations := []Migration{
+		{Name: "external", External: &External{Command: "touch", Args: []string{"{}"}, Statement: touched}},
+		{Name: "chunked", OutsideTransaction: true, Up: func(*dbr.Tx) error {
+			chunked = true
+			return nil
+		}},
+		{Name: "plain", Up: noop},
+	}
+	results, err := mM.DryRun(mM.Connection.NewSession(nil), migrations)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 3 != len(results) || !results[0].Skipped || !results[1].Skipped || results[2].Skipped || nil != results[2].Err {
+		t.Errorf("expected only the plain migration to be applied, got %+v", results)
+	}
+	if _, err = os.Stat(touched); !os.IsNotExist(err) {
+		t.Error("expected the external command not to run")
+	}
+	if chunked {
+		t.Error("expected the Up of the migration working outside its transaction not to run")
+	}
+}
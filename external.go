@@ -0,0 +1,60 @@
+package gomigration
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/gocraft/dbr"
+)
+
+// External runs the statement of a migration with an external tool instead of the database connection, e.g.
+// pt-online-schema-change for ALTERs of big MySQL tables without downtime. The tool has to be installed on the host
+// running the migrations. It works outside of the migration's transaction, so it is not rolled back if a later step
+// of the migration fails, only the row in the meta table is.
+type External struct {
+	// Command is the executable to run.
+	Command string
+	// Args are passed to Command, "{}" in them is replaced by Statement.
+	Args      []string
+	Statement string
+}
+
+// ExternalError is the error of a failed External command together with its output.
+type ExternalError struct {
+	Command string
+	Output  string
+	Err     error
+}
+
+func (e *ExternalError) Error() string {
+	return fmt.Sprintf("%s failed: %s\n%s", e.Command, e.Err, e.Output)
+}
+
+// Unwrap returns the error of running the command.
+func (e *ExternalError) Unwrap() error {
+	return e.Err
+}
+
+// PTOnlineSchemaChange returns an External running alter, the part of an ALTER TABLE statement after the table name
+// like "ADD COLUMN note TEXT", with pt-online-schema-change on the table given by dsn, e.g. "h=db,D=shop,t=orders".
+func PTOnlineSchemaChange(dsn, alter string) *External {
+	return &External{
+		Command:   "pt-online-schema-change",
+		Args:      []string{"--alter", "{}", "--execute", dsn},
+		Statement: alter,
+	}
+}
+
+// run executes the command and returns an *ExternalError with its combined output if it fails.
+func (e External) run(*dbr.Tx) error {
+	args := make([]string, 0, len(e.Args))
+	for _, arg := range e.Args {
+		args = append(args, strings.Replace(arg, "{}", e.Statement, -1))
+	}
+	output, err := exec.Command(e.Command, args...).CombinedOutput()
+	if nil != err {
+		return &ExternalError{Command: e.Command, Output: string(output), Err: err}
+	}
+	return nil
+}
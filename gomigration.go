@@ -2,151 +2,1356 @@
 package gomigration
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gocraft/dbr"
 )
 
+// defaultTableName is the meta table used when no other name was given.
+const defaultTableName = "dbMigrations"
+
+// MigrationError is returned when the Up or Down of a migration fails or panics.
+type MigrationError struct {
+	Name   string
+	Source string
+	// Direction is "up", "down", "precheck" or "verify".
+	Direction string
+	Err       error
+}
+
+// migrationError returns a *MigrationError for migration.
+func migrationError(migration Migration, direction string, err error) *MigrationError {
+	return &MigrationError{Name: migration.Name, Source: migration.Source, Direction: direction, Err: err}
+}
+
+func (e *MigrationError) Error() string {
+	if "" != e.Source {
+		return fmt.Sprintf("migration \"%s\" (%s) failed running %s: %s", e.Name, e.Source, e.Direction, e.Err)
+	}
+	return fmt.Sprintf("migration \"%s\" failed running %s: %s", e.Name, e.Direction, e.Err)
+}
+
+// Unwrap returns the error of the migration.
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+// AheadError is returned by the runners with RejectUnknown set when the meta table records migrations that are not part
+// of the code, e.g. after deploying an older version of an application whose database was already migrated further.
+type AheadError struct {
+	Names []string
+}
+
+func (e *AheadError) Error() string {
+	return fmt.Sprintf("database is ahead of the code, unknown executed migrations: %s", strings.Join(e.Names, ", "))
+}
+
+// ErrTimeout is returned when the database could not be reached within the Timeout of the MigrationManager.
+var ErrTimeout = errors.New("database did not respond within the timeout")
+
+// ErrIrreversible is returned by the Down of migrations that can not be undone.
+var ErrIrreversible = errors.New("migration is irreversible")
+
+// ErrAlreadyApplied is returned by the Precheck of a migration whose changes are present already.
+var ErrAlreadyApplied = errors.New("migration was applied already")
+
+// ErrAlreadyExecuted is returned by RunSingleMigrationUp for a migration that ran before if OnExecuted is FailExecuted.
+var ErrAlreadyExecuted = errors.New("migration was executed before")
+
+// ExecutedReaction selects what RunSingleMigrationUp does with a migration that was executed before.
+type ExecutedReaction int
+
+const (
+	// SkipExecuted silently skips the migration. It is the default.
+	SkipExecuted ExecutedReaction = iota
+	// WarnExecuted skips the migration and warns through the Logger.
+	WarnExecuted
+	// FailExecuted returns an error wrapping ErrAlreadyExecuted.
+	FailExecuted
+)
+
+// DefaultInitWait is the InitWait of MigrationManagers returned by the constructors.
+const DefaultInitWait = 250 * time.Millisecond
+
+// initRetryInterval is the pause between two attempts of IsInitialized.
+const initRetryInterval = 50 * time.Millisecond
+
+// timeFormat is the format of the DATETIME columns written by the MigrationManager.
+const timeFormat = "2006-01-02 15:04:05"
+
 type (
+	// Migrate changes the database using only the given transaction, it must not begin, commit or roll back
+	// transactions itself because the MigrationManager takes care of that.
 	Migrate   func(*dbr.Tx) error
 	Migration struct {
 		Name     string
 		Up, Down Migrate
+		// Source, if set, tells where the migration is defined, like "migrations/users.go:42", and is included in its
+		// errors. The loaders set it to the path of the up file.
+		Source string
+
+		// Baseline marks a migration as executed without running Up, for adopting gomigration on a database whose schema
+		// already contains the migration's changes. It is meant for a single run: as long as it is set every fresh
+		// database skips Up too, so remove it once all existing databases recorded the migration.
+		Baseline bool
+		// ShouldRun, if set, decides before Up runs if the migration is applied at all. When it returns false, Up is
+		// skipped and the migration is marked as executed anyway, so it is never considered again.
+		ShouldRun func(*dbr.Tx) (bool, error)
+		// SkipWithoutMarking leaves a migration skipped by ShouldRun pending instead, so ShouldRun is asked again on the
+		// next run.
+		SkipWithoutMarking bool
+		// RequiresApproval keeps the runners from applying the migration while it is pending, e.g. for changes that
+		// have to pass a change-management gate. It is left unmarked and reported by Run as awaiting approval, see
+		// StopAtApproval for what happens with the migrations after it. RunByName applies it once it was approved.
+		RequiresApproval bool
+		// Precheck, if set, runs right before Up to detect that the migration was applied partially, which happens on
+		// MySQL when a later DDL statement of Up failed after earlier ones were committed implicitly. Returning
+		// ErrAlreadyApplied skips Up and marks the migration as executed, any other error fails the migration without
+		// running Up, which suits partial states that need a manual fix. Typical prechecks look for the last object Up
+		// creates in information_schema, returning ErrAlreadyApplied if it exists and an error if only the first does.
+		Precheck Migrate
+		// Group couples migrations that form one logical change: when a migration of the group fails, MigrationRunner
+		// and MigrationRunnerContext run the Down of the migrations of the group they applied before in reverse order,
+		// making the group best-effort atomic even with MySQL's non-transactional DDL. This relies on correct Down
+		// functions. Groups are not rolled back in batches, see CommitEvery and AllInOneTransaction, by RunOnly or where
+		// the Environment forbids running Down.
+		Group string
+		// Verify, if set, runs after Up within the same transaction to check that the migration achieved its goal.
+		// If it fails, the migration is rolled back and not marked as executed.
+		Verify Migrate
+		// Isolation overrides the isolation level of the MigrationManager for the transaction of this migration.
+		Isolation sql.IsolationLevel
+		// Irreversible marks a migration whose Down only returns ErrIrreversible.
+		Irreversible bool
+		// Meta is free-form information like author or ticket stored as JSON in the meta column when the migration is
+		// marked as executed, see ExecutedMigration.Metadata. Meta tables created before that column existed need it
+		// added first:
+		//	ALTER TABLE `dbMigrations` ADD meta TEXT NULL
+		Meta map[string]string
+		// Replaces lists the names of migrations this one supersedes, e.g. a fixed version of a migration that was already
+		// deployed. A database on which one of them ran gets this migration marked as executed without running Up,
+		// on other databases Up runs and the replaced names are marked as executed as well. Rolling it back also marks
+		// the replaced names as not executed. To adopt, remove the replaced migrations from the code and add the new one.
+		// CheckIfSane rejects replaced names that are still listed as migrations or replaced by two migrations.
+		Replaces []string
+		// Checksum identifies the content of the migration, the loaders set it to the SHA-256 of the up file. It is
+		// stored when the migration is marked as executed so VerifyChecksums can detect later edits. Meta tables
+		// created before that column existed need it added first:
+		//	ALTER TABLE `dbMigrations` ADD checksum VARCHAR(64) NULL
+		Checksum string
+		// External, if set, is run instead of Up, e.g. PTOnlineSchemaChange. The migration is marked as executed within
+		// its transaction once the command succeeded.
+		External *External
+		// OutsideTransaction marks a migration whose Up changes the database outside of its transaction, like the
+		// chunks committed by Chunked, so DryRun does not apply it. Migrations with External are treated the same way.
+		OutsideTransaction bool
+		// MinServerVersion, if set, is the oldest version of the database server the migration works with, e.g. "8.0"
+		// for MySQL window functions. On older servers it fails with a clear error before Up runs.
+		MinServerVersion string
+		// IgnoreErrors, if set, decides if an error returned by Up counts as success, e.g. "table already exists" of a
+		// rerun after a previous attempt was partially applied by non-transactional MySQL DDL. The migration is then
+		// marked as executed although Up stopped at the ignored error, so a too broad predicate hides real failures and
+		// leaves the remaining statements of Up unapplied. On Postgres an error aborts the transaction anyway.
+		IgnoreErrors func(error) bool
+		// ExpectedAffected, if not zero, is the number of rows Up has to affect, otherwise the migration is rolled back.
+		// Statements of sql files are counted automatically, migrations written in Go report their results with
+		// AddAffected. It only works in transactions begun by the MigrationManager, not in MigrationRunnerTx.
+		ExpectedAffected int64
+		// Weight is the relative effort of applying the migration reported to Progress, e.g. 50 for a backfill taking
+		// as long as fifty small DDL statements. Zero or less counts as 1.
+		Weight int
+
+		// upFile and downFile are set for migrations loaded from sql files.
+		upFile, downFile string
 	}
 	MigrationManager struct {
 		Connection *dbr.Connection
 		tableName  string
+
+		// RecordFailures stores every failed attempt to apply a migration in the table returned by FailuresTableName.
+		RecordFailures bool
+		// AllInOneTransaction makes MigrationRunner create the meta table and apply all pending migrations in a single
+		// transaction, so a fresh database gets either everything or nothing. This only holds on databases with
+		// transactional DDL like Postgres, MySQL implicitly commits after every DDL statement.
+		// To include the meta table, build the MigrationManager directly instead of using a constructor that calls Init.
+		AllInOneTransaction bool
+		// CommitEvery makes MigrationRunner apply the pending migrations in transactions of this many migrations, also
+		// when AllInOneTransaction is set. If a migration fails, only its own batch is rolled back while all earlier
+		// batches stay committed. Zero keeps one transaction per migration, or one for all with AllInOneTransaction.
+		CommitEvery int
+		// AllInOneRollback makes RollbackN and RollbackAll undo all migrations in a single transaction, so a failing Down
+		// leaves every migration in place. Like AllInOneTransaction this only holds on databases with transactional DDL,
+		// on MySQL the Downs before the failing one stay undone.
+		AllInOneRollback bool
+		// VersionMode selects what Version returns, VersionHead by default.
+		VersionMode VersionMode
+		// Dialect selects the sql of the meta tables, MySQL by default. Pass it to the constructors with WithDialect.
+		Dialect Dialect
+		// ReadConnection, if set, serves status reads like CheckIfExecuted, ListExecuted and Version instead of the
+		// session passed to them, e.g. to offload them to a replica. Such reads may be stale due to replication lag,
+		// so a migration applied just now can still appear as not executed. Runners and rollbacks always read from
+		// Connection.
+		ReadConnection *dbr.Connection
+		// Isolation is the isolation level of the transactions migrations run in, the database's default if unset.
+		// It is passed on to the driver, e.g. go-sql-driver/mysql issues SET TRANSACTION ISOLATION LEVEL before
+		// START TRANSACTION while lib/pq uses BEGIN ISOLATION LEVEL.
+		Isolation sql.IsolationLevel
+		// InitWait is how long IsInitialized keeps retrying while the meta table does not exist. Some managed or
+		// replicated MySQL setups do not show a just created table to other sessions right away.
+		InitWait time.Duration
+		// QueryReceiver, if set, receives the dbr events of every migration the runners apply, e.g. a QueryLogger or a
+		// SQLAuditLog.
+		QueryReceiver MigrationReceiver
+		// Metrics, if set, is told about every migration the runners apply, skip or fail to apply.
+		Metrics Metrics
+		// Locking makes MigrationRunner hold the advisory lock returned by AcquireLock while it runs.
+		Locking bool
+		// LockTimeout is how long AcquireLock waits for another process to release the lock.
+		LockTimeout time.Duration
+		// LockConnection, if set, provides the connection the advisory lock is held on instead of Connection. The lock
+		// lives as long as that single connection, which is pinned from its pool until the lock is released, so it
+		// should reach the database directly and not through a pooler like pgbouncer in transaction mode or a proxy
+		// that recycles server connections, which would silently drop the lock while migrations run.
+		LockConnection *sql.DB
+		// NameNormalizer, if set, turns migration names into the canonical name stored in and compared with the meta
+		// table, e.g. BaseName strips directories and extensions left by loaders. Names are stored unchanged otherwise.
+		NameNormalizer func(string) string
+		// SchemaCheck selects how Init verifies an already existing meta table, SchemaCheckColumns by default. Pass it to
+		// the constructors with WithSchemaCheck.
+		SchemaCheck SchemaCheck
+		// SoftDelete makes MarkAsNotExecuted set the rolled_back_at column instead of deleting the row, keeping the
+		// history of ups and downs. Rows with rolled_back_at set count as not executed, applying the migration again
+		// adds a new row. Meta tables created before that column existed need it added first:
+		//	ALTER TABLE `dbMigrations` ADD rolled_back_at DATETIME NULL
+		SoftDelete bool
+		// PartitionColumn, if set, limits the MigrationManager to the rows of a meta table shared by several databases,
+		// e.g. of a sharded or multi-tenant setup, whose PartitionColumn equals PartitionValue. Lookups only see these
+		// rows and marking writes PartitionValue into that column, which has to be added to the meta table by hand.
+		// ExportState does not write the column.
+		PartitionColumn string
+		PartitionValue  interface{}
+		// RejectUnknown makes the runners fail with an *AheadError before applying anything if the meta table records a
+		// migration missing from the given migrations. Without it such rows are ignored, as forward-only setups expect.
+		RejectUnknown bool
+		// Keys selects how the id column of the meta table is created and filled, KeyAutoIncrement by default.
+		// It only applies to meta tables created by the MigrationManager with that setting, so pass it to the
+		// constructors with WithKeys.
+		Keys KeyStrategy
+		// Timeout, if set, is how long Init, IsInitialized and AssertUpToDate wait for the database to respond to a ping
+		// before failing with ErrTimeout, so e.g. readiness probes return quickly while the database is down. It bounds
+		// reaching the database, not the duration of the queries that follow.
+		Timeout time.Duration
+		// Logger, if set, receives warnings of the MigrationManager, e.g. a *log.Logger.
+		Logger Logger
+		// SlowThreshold, if set, makes the runners warn through Logger about every migration that took longer to apply.
+		SlowThreshold time.Duration
+		// CreateTableSQL, if set, replaces the built-in statement creating the meta table, e.g. to choose engine,
+		// charset or extra indexes. It has to mention the table name, must not fail if the table exists, like
+		// CREATE TABLE IF NOT EXISTS, and needs at least the columns id, name and execution, which Init verifies even
+		// with SchemaCheckOff. Columns of optional features like SoftDelete or Meta have to be included if used. Pass it
+		// to the constructors with WithCreateTableSQL.
+		CreateTableSQL string
+		// OnResult, if set, is called with the last result recorded by RecordResult after the Up or Down of a migration
+		// succeeded, before its transaction is committed, e.g. to log how many rows the last statement of a sql file
+		// backfilled. It is not called for migrations that recorded no result.
+		OnResult func(name, direction string, result sql.Result)
+		// UpdateChecksums makes VerifyChecksums store the current checksum of edited migrations and warn through Logger
+		// instead of failing, for intentional edits that do not change the outcome of the migration.
+		UpdateChecksums bool
+		// VerifyChecksumsBeforeRun makes MigrationRunner and MigrationRunnerContext call VerifyChecksums before applying
+		// anything and fail if an executed migration was edited, turning drift detection into a deploy gate. Only
+		// migrations with a Checksum, like the ones loaded from sql files, are checked, closures without one are
+		// skipped. With UpdateChecksums edits are only reported through Logger.
+		VerifyChecksumsBeforeRun bool
+		// Progress, if set, is called by the runners after each migration they applied with the summed Weight of the
+		// migrations applied so far and of the migrations that were pending when the run started, so done/total is the
+		// completed fraction. Without weights these are the numbers of migrations.
+		Progress func(done, total int)
+		// Release, if set, is stored in the version column of every migration applied by this MigrationManager, e.g. the
+		// release or git commit of the application, to find out which release introduced a migration. Meta tables
+		// created before that column existed need it added first:
+		//	ALTER TABLE `dbMigrations` ADD version VARCHAR(255) NULL
+		Release string
+		// DeploymentID, if set, is stored in the deployment_id column of every migration applied by this
+		// MigrationManager, e.g. the id of the deploy running the migrations, so History and DeploymentHistory show
+		// what a deployment changed. Meta tables created before that column existed need it added first:
+		//	ALTER TABLE `dbMigrations` ADD deployment_id VARCHAR(255) NULL
+		DeploymentID string
+		// RecordDurations makes the runners store how long applying each migration took in the duration_ms column,
+		// which History reports. Meta tables created before that column existed need it added first:
+		//	ALTER TABLE `dbMigrations` ADD duration_ms BIGINT NULL
+		// Pass it to the constructors with WithRecordDurations.
+		RecordDurations bool
+		// Confirm gates the destructive operations: TeardownAll always, RollbackN and RollbackAll only if the Down of one
+		// of the migrations they undo is destructive, see IsDestructiveDown. They call it with their name and fail with
+		// ErrNotConfirmed without touching anything unless it returns true. As long as it is not set they fail whenever
+		// they need it. RunSingleMigrationDown is not gated.
+		Confirm func(operation string) bool
+		// Environment names where the MigrationManager runs. In ProductionEnvironment ("production", in any case) all
+		// operations running Down, RunSingleMigrationDown, RollbackN, RollbackAll and TeardownAll, fail with
+		// ErrRollbackDisabled, enforcing forward-only migrations. Fixing a broken deploy usually means a new migration.
+		Environment string
+		// AllowDown overrides the production Environment for a genuine emergency. Set it only on the MigrationManager of
+		// the one-off tool or process doing the rollback, never in the regular configuration of the application.
+		AllowDown bool
+		// OrderBy selects the order operations depending on the order of executed migrations use, OrderByID by default.
+		// Init rejects unknown values, pass it to the constructors with WithOrderBy to have them checked right away.
+		OrderBy Ordering
+		// StopAtApproval makes the runners stop at the first pending migration with RequiresApproval, leaving it and
+		// all following migrations pending, for migrations that depend on their predecessors. By default the runners
+		// skip such a migration and continue with the following ones, which must not depend on it then. Plan and
+		// AssertUpToDate follow the same rule.
+		StopAtApproval bool
+		// DelayBetween, if positive, makes MigrationRunner and MigrationRunnerContext pause between two migrations they
+		// apply, e.g. to let replicas catch up on a busy database. Cancelling the context ends the pause, returning
+		// ErrInterrupted. Batches, see CommitEvery and AllInOneTransaction, are not throttled.
+		DelayBetween time.Duration
+		// OnExecuted selects what RunSingleMigrationUp, and with it RunOnly and RunByName, does when asked to apply a
+		// migration that was executed before, to catch accidental re-runs in custom orchestration. The runners always
+		// skip executed migrations silently.
+		OnExecuted ExecutedReaction
+		// Tracer, if set, gets a span around every run of MigrationRunnerContext and a child span for each migration it
+		// applies, tagged with the name of the migration and its outcome.
+		Tracer Tracer
+		// TablePrefix lets one set of migrations serve several installs in the same database whose tables carry a
+		// per-install prefix. It replaces PrefixPlaceholder in the statements of sql files, migrations written in Go get
+		// it through TablePrefix or PrefixedName. The meta table, its failures table and the lock key are prefixed as
+		// well, so each install keeps its own state, and adding a prefix to an existing install means renaming its meta
+		// table accordingly. Pass it to the constructors with WithTablePrefix, so Init creates the prefixed meta table.
+		TablePrefix string
+		// Schema, if set, is the Postgres schema migrations run in, e.g. for schema-per-tenant designs. Every
+		// transaction begun for a migration sets its search_path to it with SET LOCAL, so migrations do not have to
+		// qualify their identifiers, and the meta table and the other tables of the MigrationManager are qualified with
+		// it. The schema has to exist and its name may only consist of lower case letters, digits and underscores.
+		// Other dialects reject it, MySQL tables are qualified with the database in the table name instead. Pass it to
+		// the constructors with WithSchema, so Init creates the meta table in the schema.
+		Schema string
 	}
 )
 
-// NewMigrationManager returns a default MigrationManager and initializes it.
-func NewMigrationManager(c *dbr.Connection) MigrationManager {
-	mM := MigrationManager{Connection: c, tableName: "dbMigrations"}
-	mM.Init()
-	return mM
+// selector is implemented by both *dbr.Session and *dbr.Tx.
+type selector interface {
+	Select(cols ...string) *dbr.SelectBuilder
+}
+
+// Option configures a MigrationManager before a constructor initializes it. It is needed for the settings Init depends
+// on, like the Dialect its meta table is created with, changing these on the returned MigrationManager comes too late.
+type Option func(*MigrationManager)
+
+// WithDialect sets the Dialect of the MigrationManager.
+func WithDialect(dialect Dialect) Option {
+	return func(mM *MigrationManager) {
+		mM.Dialect = dialect
+	}
+}
+
+// WithTableName sets the name of the meta table, which has to be a plain identifier as it is used unquoted.
+func WithTableName(tableName string) Option {
+	return func(mM *MigrationManager) {
+		mM.tableName = tableName
+	}
+}
+
+// WithSchemaCheck sets how strictly Init verifies an already existing meta table.
+func WithSchemaCheck(check SchemaCheck) Option {
+	return func(mM *MigrationManager) {
+		mM.SchemaCheck = check
+	}
+}
+
+// WithKeys sets how the id column of the meta table is created and filled.
+func WithKeys(keys KeyStrategy) Option {
+	return func(mM *MigrationManager) {
+		mM.Keys = keys
+	}
+}
+
+// WithCreateTableSQL sets the statement Init creates the meta table with instead of the built-in one.
+func WithCreateTableSQL(statement string) Option {
+	return func(mM *MigrationManager) {
+		mM.CreateTableSQL = statement
+	}
+}
+
+// WithOrderBy sets the order operations depending on the order of executed migrations use.
+func WithOrderBy(order Ordering) Option {
+	return func(mM *MigrationManager) {
+		mM.OrderBy = order
+	}
+}
+
+// WithTablePrefix sets the prefix of the tables of the install the MigrationManager migrates.
+func WithTablePrefix(prefix string) Option {
+	return func(mM *MigrationManager) {
+		mM.TablePrefix = prefix
+	}
+}
+
+// WithSchema sets the Postgres schema migrations run in.
+func WithSchema(schema string) Option {
+	return func(mM *MigrationManager) {
+		mM.Schema = schema
+	}
+}
+
+// WithRecordDurations makes the MigrationManager record how long applying each migration took.
+func WithRecordDurations() Option {
+	return func(mM *MigrationManager) {
+		mM.RecordDurations = true
+	}
 }
 
-// NewMigrationManagerExplicitTableName returns a new MigrationManager with a named migration-meta-data table and initializes it.
-func NewMigrationManagerExplicitTableName(c *dbr.Connection, tableName string) MigrationManager {
-	mM := MigrationManager{Connection: c, tableName: tableName}
+// NewMigrationManager returns a default MigrationManager configured by options and initializes it.
+func NewMigrationManager(c *dbr.Connection, options ...Option) MigrationManager {
+	return NewMigrationManagerExplicitTableName(c, defaultTableName, options...)
+}
+
+// NewMigrationManagerExplicitTableName returns a new MigrationManager with a named migration-meta-data table configured
+// by options and initializes it.
+func NewMigrationManagerExplicitTableName(c *dbr.Connection, tableName string, options ...Option) MigrationManager {
+	mM := MigrationManager{Connection: c, tableName: tableName, InitWait: DefaultInitWait, LockTimeout: DefaultLockTimeout}
+	for _, option := range options {
+		option(&mM)
+	}
 	mM.Init()
 	return mM
 }
 
+// NewModuleMigrationManager returns a new MigrationManager tracking the migrations of a single module of a plugin
+// architecture in its own meta table "dbMigrations_<module>" configured by options and initializes it. Modules are
+// independent of each other, they may use the same migration names and lock separately. Uninstalling a module rolls
+// back just its migrations with RollbackAll of its MigrationManager.
+func NewModuleMigrationManager(c *dbr.Connection, module string, options ...Option) MigrationManager {
+	return NewMigrationManagerExplicitTableName(c, ModuleTableName(module), options...)
+}
+
+// ModuleTableName returns the name of the meta table of module.
+func ModuleTableName(module string) string {
+	return defaultTableName + "_" + module
+}
+
 // Init initializes the necessary DbTable for the migrations and panics if not successful.
+// An already existing table is verified by CheckSchema.
 func (mM MigrationManager) Init() {
-	session := mM.Connection.NewSession(nil)
-	transaction, err := session.Begin()
-	if nil != err {
+	if err := mM.initialize(mM.Connection.NewSession(nil)); nil != err {
 		panic(err)
 	}
-	_, err = transaction.Exec("CREATE TABLE IF NOT EXISTS `" + mM.tableName + "` " + `(
-				id INT NOT NULL AUTO_INCREMENT,
-				name VARCHAR(255),
-				execution DATETIME,
-				PRIMARY KEY (id)		
-		)`)
+}
+
+// initialize creates the meta table unless it exists and verifies it by CheckSchema.
+func (mM MigrationManager) initialize(session *dbr.Session) error {
+	if err := mM.checkSchemaName(); nil != err {
+		return err
+	}
+	if err := mM.checkOrdering(); nil != err {
+		return err
+	}
+	if err := mM.reachable(); nil != err {
+		return err
+	}
+	transaction, err := session.Begin()
 	if nil != err {
+		return err
+	}
+	if err = mM.createTable(transaction); nil != err {
 		transaction.Rollback()
-		panic(err)
+		return err
 	}
 	err = transaction.Commit()
 	if nil != err {
 		transaction.Rollback()
 	}
+	if "" != mM.CreateTableSQL && SchemaCheckOff == mM.SchemaCheck {
+		mM.SchemaCheck = SchemaCheckColumns
+	}
+	return mM.CheckSchema()
+}
+
+// ensureInitialized initializes a MigrationManager that was built by hand without calling Init, before a runner starts
+// working with a meta table that does not exist.
+func (mM MigrationManager) ensureInitialized(session *dbr.Session) error {
+	initialized, err := mM.IsInitialized(session)
+	if nil != err || initialized {
+		return err
+	}
+	return mM.initialize(session)
+}
+
+// IsInitialized checks if the meta table exists. A missing table is retried for up to InitWait before false is returned.
+func (mM MigrationManager) IsInitialized(session *dbr.Session) (bool, error) {
+	if err := mM.reachable(); nil != err {
+		return false, err
+	}
+	deadline := time.Now().Add(mM.InitWait)
+	for {
+		_, err := session.Select("count(*)").From(mM.table()).ReturnInt64()
+		if nil == err {
+			return true, nil
+		}
+		if !isMissingTable(err) {
+			return false, err
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		time.Sleep(initRetryInterval)
+	}
+}
+
+// reachable pings the database with Timeout set and returns ErrTimeout if it does not answer in time.
+func (mM MigrationManager) reachable() error {
+	if 0 >= mM.Timeout {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), mM.Timeout)
+	defer cancel()
+	err := mM.Connection.Db.PingContext(ctx)
+	if nil != err && context.DeadlineExceeded == ctx.Err() {
+		return ErrTimeout
+	}
+	return err
+}
+
+// isMissingTable checks if err is the error of MySQL, Postgres or SQLite for a table that does not exist.
+func isMissingTable(err error) bool {
+	message := err.Error()
+	return strings.Contains(message, "doesn't exist") || strings.Contains(message, "does not exist") ||
+		strings.Contains(message, "no such table")
+}
+
+// createTable creates the meta table within transaction unless it already exists.
+func (mM MigrationManager) createTable(transaction *dbr.Tx) (rErr error) {
+	if "" != mM.CreateTableSQL {
+		if !strings.Contains(mM.CreateTableSQL, mM.table()) {
+			return errors.New(fmt.Sprintf("CreateTableSQL does not mention the meta table \"%s\"", mM.table()))
+		}
+		_, rErr = transaction.Exec(mM.CreateTableSQL)
+		return
+	}
+	_, rErr = transaction.Exec(createTableSQL(mM.table(), mM.Dialect, mM.Keys))
+	return
+}
+
+// TableName returns the name of the meta table, which is "dbMigrations" unless another name was given.
+func (mM MigrationManager) TableName() string {
+	return mM.table()
+}
+
+// table returns the name of the meta table, falling back to the default for a MigrationManager built by hand.
+func (mM MigrationManager) table() string {
+	name := mM.tableName
+	if "" == name {
+		name = defaultTableName
+	}
+	if "" != mM.Schema {
+		return mM.Schema + "." + mM.TablePrefix + name
+	}
+	return mM.TablePrefix + name
 }
 
 // MarkAsExecuted marks that a single Migration was applied.
 func (mM MigrationManager) MarkAsExecuted(transaction *dbr.Tx, migration Migration) (rErr error) {
-	t := time.Now().Format("2006-01-02 15:04:05")
-	_, rErr = transaction.InsertInto(mM.tableName).Pair("name", migration.Name).Pair("execution", t).Exec()
+	return mM.MarkManyAsExecuted(transaction, []Migration{migration})
+}
+
+// maxMetaSize is the number of bytes fitting into the TEXT meta column of MySQL.
+const maxMetaSize = 65535
+
+// metaJSON returns the Meta of migration encoded as JSON, or nil if it has none.
+func metaJSON(migration Migration) (interface{}, error) {
+	if 0 == len(migration.Meta) {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(migration.Meta)
+	if nil != err {
+		return nil, err
+	}
+	if len(encoded) > maxMetaSize {
+		return nil, errors.New(fmt.Sprintf("meta of migration \"%s\" has %d bytes but at most %d fit the meta column", migration.Name, len(encoded), maxMetaSize))
+	}
+	return string(encoded), nil
+}
+
+// MarkManyAsExecuted marks all migrations as applied with a single multi-row INSERT, so either all or none of them are
+// recorded. It is meant for baselining an existing schema, Up is not run.
+func (mM MigrationManager) MarkManyAsExecuted(transaction *dbr.Tx, migrations []Migration) (rErr error) {
+	return mM.markExecuted(transaction, migrations, 0)
+}
+
+// markExecuted marks migrations as applied, recording duration as the time applying them took if it is set and
+// RecordDurations is enabled.
+func (mM MigrationManager) markExecuted(transaction *dbr.Tx, migrations []Migration, duration time.Duration) (rErr error) {
+	if 0 == len(migrations) {
+		return nil
+	}
+	t := time.Now().Format(timeFormat)
+	rows := make([]map[string]interface{}, 0, len(migrations))
+	columns := []string{"name", "execution"}
+	for _, migration := range migrations {
+		row, err := mM.markValues(migration)
+		if nil != err {
+			return err
+		}
+		row["name"], row["execution"] = mM.normalize(migration.Name), t
+		if mM.RecordDurations && 0 < duration {
+			row["duration_ms"] = int64(duration / time.Millisecond)
+		}
+		for _, column := range markColumns {
+			if _, ok := row[column]; ok && !containsString(columns, column) {
+				columns = append(columns, column)
+			}
+		}
+		rows = append(rows, row)
+	}
+	if "" != mM.PartitionColumn {
+		columns = append(columns, mM.PartitionColumn)
+	}
+	builder := transaction.InsertInto(mM.table()).Columns(columns...)
+	for _, row := range rows {
+		values := make([]interface{}, 0, len(columns))
+		for _, column := range columns {
+			if mM.PartitionColumn == column {
+				values = append(values, mM.PartitionValue)
+				continue
+			}
+			values = append(values, row[column])
+		}
+		builder = builder.Values(values...)
+	}
+	_, rErr = builder.Exec()
 	return
 }
 
-// MarkAsNotExecuted deletes the entry of an migration that was previously applied.
+// markColumns are the optional columns of the meta table written when a migration is marked as executed, in the
+// order they are inserted.
+var markColumns = []string{"id", "meta", "checksum", "version", "deployment_id", "duration_ms"}
+
+// markValues returns the values of the markColumns recorded for migration, leaving out those it has no value for.
+func (mM MigrationManager) markValues(migration Migration) (map[string]interface{}, error) {
+	row := make(map[string]interface{})
+	if KeyUUID == mM.Keys {
+		id, err := newUUID()
+		if nil != err {
+			return nil, err
+		}
+		row["id"] = id
+	}
+	if 0 < len(migration.Meta) {
+		meta, err := metaJSON(migration)
+		if nil != err {
+			return nil, err
+		}
+		row["meta"] = meta
+	}
+	if "" != migration.Checksum {
+		row["checksum"] = migration.Checksum
+	}
+	if "" != mM.Release {
+		row["version"] = mM.Release
+	}
+	if "" != mM.DeploymentID {
+		row["deployment_id"] = mM.DeploymentID
+	}
+	return row, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, element := range list {
+		if element == s {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkAsNotExecuted deletes the entry of an migration that was previously applied, or flags it with SoftDelete set.
 func (mM MigrationManager) MarkAsNotExecuted(transaction *dbr.Tx, migration Migration) (rErr error) {
-	_, rErr = transaction.DeleteFrom(mM.tableName).Where("name = ?", migration.Name).Exec()
+	if mM.SoftDelete {
+		builder := transaction.Update(mM.table()).Set("rolled_back_at", time.Now().Format(timeFormat)).
+			Where(nameEquals(mM.Dialect), mM.normalize(migration.Name)).Where("rolled_back_at IS NULL")
+		if "" != mM.PartitionColumn {
+			builder = builder.Where(mM.partitionEquals(), mM.PartitionValue)
+		}
+		_, rErr = builder.Exec()
+		return
+	}
+	builder := transaction.DeleteFrom(mM.table()).Where(nameEquals(mM.Dialect), mM.normalize(migration.Name))
+	if "" != mM.PartitionColumn {
+		builder = builder.Where(mM.partitionEquals(), mM.PartitionValue)
+	}
+	_, rErr = builder.Exec()
 	return
 }
 
 // CheckIfExecuted checks if an migration ran before and returns true if yes and otherwise false.
+// Names are compared case-sensitively, so "InitUser" and "inituser" are different migrations.
 func (mM MigrationManager) CheckIfExecuted(session *dbr.Session, migration Migration) bool {
-	amount, _ := session.Select("count(*)").From(mM.tableName).Where("name = ?", migration.Name).ReturnInt64()
+	return mM.checkIfExecuted(mM.readSession(session), migration)
+}
+
+// CheckIfExecutedBatch checks for each of migrations if it ran before with a single query and returns the result by
+// migration name, e.g. for custom runners and dashboards. Unlike CheckIfExecuted it returns the error of the query.
+func (mM MigrationManager) CheckIfExecutedBatch(session *dbr.Session, migrations []Migration) (map[string]bool, error) {
+	executed, err := mM.executedSet(mM.readSession(session))
+	if nil != err {
+		return nil, err
+	}
+	result := make(map[string]bool, len(migrations))
+	for _, migration := range migrations {
+		result[migration.Name] = executed[mM.normalize(migration.Name)]
+	}
+	return result, nil
+}
+
+// readSession returns a session of ReadConnection if one is configured and session otherwise.
+func (mM MigrationManager) readSession(session *dbr.Session) *dbr.Session {
+	if nil == mM.ReadConnection {
+		return session
+	}
+	return mM.ReadConnection.NewSession(nil)
+}
+
+func (mM MigrationManager) checkIfExecuted(s selector, migration Migration) bool {
+	amount, _ := mM.selectExecuted(s, "count(*)").Where(nameEquals(mM.Dialect), mM.normalize(migration.Name)).ReturnInt64()
+	if amount > 1 {
+		mM.warn("migration \"%s\" is recorded %d times in the meta table, see CheckIntegrity and Repair", migration.Name, amount)
+	}
 	return amount > 0
 }
 
-// CheckIfSane checks if the list of migrations has any name twice and stops on first error or returns nil.
+// CheckIfSane checks if the list of migrations has any name twice, compared after NameNormalizer, and stops on first
+// error or returns nil.
+// Earlier versions never detected a name listed twice, so lists with duplicates that used to run now make the
+// runners fail before applying anything.
 func (mM MigrationManager) CheckIfSane(migrations []Migration) error {
 	list := make(map[string]bool)
 	for _, m := range migrations {
-		if _, double := list[m.Name]; double {
+		name := mM.normalize(m.Name)
+		if _, double := list[name]; double {
 			return errors.New(fmt.Sprintf("migrations name must be unique but migration \"%s\" exists at least twice", m.Name))
 		}
+		list[name] = true
+	}
+	replacedBy := make(map[string]string)
+	for _, m := range migrations {
+		for _, replaced := range m.Replaces {
+			replaced = mM.normalize(replaced)
+			if list[replaced] {
+				return errors.New(fmt.Sprintf("migration \"%s\" replaces \"%s\" which is still a migration", m.Name, replaced))
+			}
+			if other, double := replacedBy[replaced]; double {
+				return errors.New(fmt.Sprintf("migration \"%s\" is replaced by both \"%s\" and \"%s\"", replaced, other, m.Name))
+			}
+			replacedBy[replaced] = m.Name
+		}
 	}
 	return nil
 }
 
-// MigrationRunner applies all migrations that have not yet been executed.
+// checkUnknown returns an *AheadError with RejectUnknown set if executed contains names missing from migrations.
+func (mM MigrationManager) checkUnknown(executed map[string]bool, migrations []Migration) error {
+	if !mM.RejectUnknown {
+		return nil
+	}
+	known := make(map[string]bool, len(migrations))
+	for _, m := range migrations {
+		known[mM.normalize(m.Name)] = true
+		for _, replaced := range m.Replaces {
+			known[mM.normalize(replaced)] = true
+		}
+	}
+	var unknown []string
+	for name := range executed {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if 0 == len(unknown) {
+		return nil
+	}
+	sort.Strings(unknown)
+	return &AheadError{Names: unknown}
+}
+
+// MigrationRunner applies all migrations that have not yet been executed and panics with the first error of
+// MigrationRunnerContext. This includes errors of CheckIfSane, which MigrationRunner ignored before it was based on
+// MigrationRunnerContext.
 func (mM MigrationManager) MigrationRunner(migrations []Migration) {
-	mM.CheckIfSane(migrations)
+	if err := mM.MigrationRunnerContext(context.Background(), migrations); nil != err {
+		panic(err)
+	}
+}
+
+// MigrationRunnerContext applies all migrations that have not yet been executed and returns the first error.
+// Once ctx is done no further migration is started, while the one running at that moment is finished, and an error
+// wrapping ErrInterrupted is returned. In batches (see CommitEvery and AllInOneTransaction) the uncommitted batch is
+// rolled back instead. See InterruptOnSignal for stopping on SIGTERM. If Locking is set the
+// advisory lock is held meanwhile and always released, even when ctx was cancelled.
+func (mM MigrationManager) MigrationRunnerContext(ctx context.Context, migrations []Migration) (rErr error) {
+	ctx, span := mM.startSpan(ctx, "gomigration.run", map[string]string{"migration.count": strconv.Itoa(len(migrations))})
+	defer func() {
+		span.End(rErr)
+	}()
+	if err := mM.CheckIfSane(migrations); nil != err {
+		return err
+	}
+	if mM.Locking {
+		lock, err := mM.acquireLock(ctx)
+		if nil != err {
+			return err
+		}
+		defer func() {
+			if err := lock.Release(); nil != err && nil == rErr {
+				rErr = err
+			}
+		}()
+	}
 	session := mM.Connection.NewSession(nil)
+	if mM.VerifyChecksumsBeforeRun {
+		if err := mM.ensureInitialized(session); nil != err {
+			return err
+		}
+		if err := mM.VerifyChecksums(session, migrations); nil != err {
+			return fmt.Errorf("not running migrations: %w", err)
+		}
+	}
+	if mM.AllInOneTransaction || 0 < mM.CommitEvery {
+		return mM.runInBatches(ctx, session, migrations, mM.CommitEvery)
+	}
+	if err := mM.ensureInitialized(session); nil != err {
+		return err
+	}
+	executed, err := mM.executedSet(session)
+	if nil != err {
+		return err
+	}
+	if err = mM.checkUnknown(executed, migrations); nil != err {
+		return err
+	}
+	total, done := mM.countPending(executed, migrations), 0
+	groups, applied := make(map[string][]Migration), false
 	for _, migration := range migrations {
-		if err := mM.RunSingleMigrationUp(session, migration); nil != err {
-			panic(err)
-			break
+		if err := interrupted(ctx, migration); nil != err {
+			return err
+		}
+		pending := !executed[mM.normalize(migration.Name)]
+		if pending && migration.RequiresApproval {
+			if mM.StopAtApproval {
+				return nil
+			}
+			continue
+		}
+		if pending && applied && 0 < mM.DelayBetween {
+			select {
+			case <-ctx.Done():
+				return interrupted(ctx, migration)
+			case <-time.After(mM.DelayBetween):
+			}
+		}
+		var span Span = nopSpan{}
+		if pending {
+			span = mM.migrationSpan(ctx, migration)
+		}
+		err := mM.RunSingleMigrationUpWith(session, migration, executed)
+		endMigrationSpan(span, err)
+		if nil != err && "" != migration.Group {
+			return mM.undoGroup(session, migration.Group, groups[migration.Group], err)
+		}
+		if nil != err {
+			return err
+		}
+		if pending {
+			if "" != migration.Group {
+				groups[migration.Group] = append(groups[migration.Group], migration)
+			}
+			done += weight(migration)
+			mM.progress(done, total)
+			applied = true
 		}
 	}
+	return nil
 }
 
-// RunSingleMigrationUp applies a single migration if it was not yet executed.
-func (mM MigrationManager) RunSingleMigrationUp(session *dbr.Session, migration Migration) error {
-	if mM.CheckIfExecuted(session, migration) {
-		return nil
+// undoGroup runs the Down of the migrations of group applied in this run in reverse order, after applying another
+// migration of group failed with failure. Where the Environment forbids running Down the group is left applied.
+func (mM MigrationManager) undoGroup(session *dbr.Session, group string, applied []Migration, failure error) error {
+	if 0 == len(applied) {
+		return failure
 	}
-	transaction, err := session.Begin()
+	if err := mM.downAllowed(); nil != err {
+		return fmt.Errorf("%w; group \"%s\" was not rolled back: %s", failure, group, err)
+	}
+	for i := len(applied) - 1; 0 <= i; i-- {
+		if err := mM.runDown(session, applied[i]); nil != err {
+			return fmt.Errorf("%w; rolling back group \"%s\" failed at \"%s\": %s", failure, group, applied[i].Name, err)
+		}
+	}
+	return fmt.Errorf("%w; group \"%s\" was rolled back", failure, group)
+}
+
+// countPending returns the summed weight of the migrations not in executed that do not await approval.
+func (mM MigrationManager) countPending(executed map[string]bool, migrations []Migration) int {
+	pending := 0
+	for _, migration := range migrations {
+		if !executed[mM.normalize(migration.Name)] && !migration.RequiresApproval {
+			pending += weight(migration)
+		}
+	}
+	return pending
+}
+
+// weight returns the Weight of migration, at least 1.
+func weight(migration Migration) int {
+	if 1 > migration.Weight {
+		return 1
+	}
+	return migration.Weight
+}
+
+// progress reports to Progress, if set.
+func (mM MigrationManager) progress(done, total int) {
+	if nil != mM.Progress {
+		mM.Progress(done, total)
+	}
+}
+
+// MigrationRunnerTx creates the meta table if needed and applies all pending migrations within transaction, which the
+// caller has to commit or roll back. This keeps migrations atomic with other work of the caller only on databases with
+// transactional DDL like Postgres. MySQL implicitly commits the caller's transaction at the first DDL statement!
+func (mM MigrationManager) MigrationRunnerTx(transaction *dbr.Tx, migrations []Migration) error {
+	if err := mM.CheckIfSane(migrations); nil != err {
+		return err
+	}
+	if err := mM.createTable(transaction); nil != err {
+		return err
+	}
+	executed, err := mM.executedSet(transaction)
 	if nil != err {
 		return err
 	}
-	err = migration.Up(transaction)
+	if err = mM.checkUnknown(executed, migrations); nil != err {
+		return err
+	}
+	total, done := mM.countPending(executed, migrations), 0
+	for _, migration := range migrations {
+		if executed[mM.normalize(migration.Name)] {
+			mM.metrics().IncSkipped()
+			continue
+		}
+		if migration.RequiresApproval {
+			if mM.StopAtApproval {
+				return nil
+			}
+			continue
+		}
+		start := time.Now()
+		err := mM.ApplyUp(transaction, migration)
+		mM.observe(migration, start, err)
+		if nil != err {
+			return err
+		}
+		executed[mM.normalize(migration.Name)] = true
+		done += weight(migration)
+		mM.progress(done, total)
+	}
+	return nil
+}
+
+// runInBatches creates the meta table and applies the pending migrations in transactions of up to size migrations,
+// or all of them in a single transaction if size is zero.
+func (mM MigrationManager) runInBatches(ctx context.Context, session *dbr.Session, migrations []Migration, size int) error {
+	transaction, monitor, err := mM.begin(session, mM.Isolation)
+	if nil != err {
+		return err
+	}
+	if err = mM.createTable(transaction); nil != err {
+		transaction.Rollback()
+		return err
+	}
+	executed, err := mM.executedSet(transaction)
 	if nil == err {
-		if err := mM.MarkAsExecuted(transaction, migration); nil != err {
+		err = mM.checkUnknown(executed, migrations)
+	}
+	if nil != err {
+		transaction.Rollback()
+		return err
+	}
+	total := mM.countPending(executed, migrations)
+	applied, done := 0, 0
+	for _, migration := range migrations {
+		if err = interrupted(ctx, migration); nil != err {
 			transaction.Rollback()
 			return err
 		}
-		if err2 := transaction.Commit(); nil != err2 {
+		if executed[mM.normalize(migration.Name)] {
+			mM.metrics().IncSkipped()
+			continue
+		}
+		if migration.RequiresApproval {
+			if mM.StopAtApproval {
+				break
+			}
+			continue
+		}
+		start, span := time.Now(), mM.migrationSpan(ctx, migration)
+		err = mM.monitored(transaction, monitor, migration, "up", mM.ApplyUp)
+		mM.observe(migration, start, err)
+		endMigrationSpan(span, err)
+		if nil != err {
 			transaction.Rollback()
-			return err2
+			if mM.RecordFailures {
+				mM.recordFailure(session, migration, err)
+			}
+			return err
+		}
+		executed[mM.normalize(migration.Name)] = true
+		applied++
+		done += weight(migration)
+		mM.progress(done, total)
+		if 0 < size && 0 == applied%size {
+			if err = transaction.Commit(); nil != err {
+				transaction.Rollback()
+				return err
+			}
+			if transaction, monitor, err = mM.begin(session, mM.Isolation); nil != err {
+				return err
+			}
 		}
-	} else {
+	}
+	if err = transaction.Commit(); nil != err {
 		transaction.Rollback()
 		return err
 	}
 	return nil
 }
 
-// RunSingleMigrationDown undos a migration if it was already applied, otherwise throws an error.
-func (mM MigrationManager) RunSingleMigrationDown(session *dbr.Session, migration Migration) error {
-	if !mM.CheckIfExecuted(session, migration) {
-		return errors.New("migration was not yet executed")
+// RunOnly applies the pending migrations whose names are listed in names, in the order of the migrations slice.
+// All names are validated before anything runs, an unknown name is an error while already executed migrations are skipped.
+func (mM MigrationManager) RunOnly(session *dbr.Session, migrations []Migration, names []string) error {
+	known := make(map[string]bool)
+	for _, m := range migrations {
+		known[m.Name] = true
 	}
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		if !known[name] {
+			return errors.New(fmt.Sprintf("unknown migration \"%s\"", name))
+		}
+		wanted[name] = true
+	}
+	if err := mM.ensureInitialized(session); nil != err {
+		return err
+	}
+	for _, migration := range migrations {
+		if !wanted[migration.Name] {
+			continue
+		}
+		if err := mM.RunSingleMigrationUp(session, migration); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunByName applies the pending migration called name regardless of RequiresApproval, e.g. after an operator
+// approved it. The migrations before it are not applied.
+func (mM MigrationManager) RunByName(session *dbr.Session, migrations []Migration, name string) error {
+	return mM.RunOnly(session, migrations, []string{name})
+}
+
+// FailuresTableName returns the name of the table failed migration attempts are recorded in.
+func (mM MigrationManager) FailuresTableName() string {
+	return mM.table() + "Failures"
+}
+
+// recordFailure stores a failed attempt in its own transaction, so it is kept even though the migration was rolled back.
+// Problems while recording are ignored so they can not hide the error of the migration itself.
+func (mM MigrationManager) recordFailure(session *dbr.Session, migration Migration, failure error) {
 	transaction, err := session.Begin()
 	if nil != err {
+		return
+	}
+	_, err = transaction.Exec(createFailuresTableSQL(mM.FailuresTableName(), mM.Dialect))
+	if nil != err {
+		transaction.Rollback()
+		return
+	}
+	t := time.Now().Format(timeFormat)
+	_, err = transaction.InsertInto(mM.FailuresTableName()).Pair("name", migration.Name).Pair("error", failure.Error()).Pair("execution", t).Exec()
+	if nil != err {
+		transaction.Rollback()
+		return
+	}
+	transaction.Commit()
+}
+
+// RunSingleMigrationUp applies a single migration if it was not yet executed, see OnExecuted for what happens otherwise.
+func (mM MigrationManager) RunSingleMigrationUp(session *dbr.Session, migration Migration) error {
+	if mM.checkIfExecuted(session, migration) {
+		switch mM.OnExecuted {
+		case FailExecuted:
+			return fmt.Errorf("%w: \"%s\"", ErrAlreadyExecuted, migration.Name)
+		case WarnExecuted:
+			mM.warn("migration \"%s\" was executed before and is not applied again", migration.Name)
+		}
+		mM.metrics().IncSkipped()
+		return nil
+	}
+	err := mM.runUp(session, migration)
+	if nil != err && mM.RecordFailures {
+		mM.recordFailure(session, migration, err)
+	}
+	return err
+}
+
+// RunSingleMigrationUpWith applies a single migration unless its normalized name is in executed, without querying the
+// meta table to find out if it ran before. On success the name is added to executed.
+func (mM MigrationManager) RunSingleMigrationUpWith(session *dbr.Session, migration Migration, executed map[string]bool) error {
+	if executed[mM.normalize(migration.Name)] {
+		mM.metrics().IncSkipped()
+		return nil
+	}
+	err := mM.runUp(session, migration)
+	if nil != err {
+		if mM.RecordFailures {
+			mM.recordFailure(session, migration, err)
+		}
 		return err
 	}
-	err = migration.Down(transaction)
-	if nil == err {
-		if err := mM.MarkAsNotExecuted(transaction, migration); nil != err {
-			transaction.Rollback()
+	executed[mM.normalize(migration.Name)] = true
+	return nil
+}
+
+// normalize returns the name migrations called name are recorded with.
+func (mM MigrationManager) normalize(name string) string {
+	if nil == mM.NameNormalizer {
+		return name
+	}
+	return mM.NameNormalizer(name)
+}
+
+// BaseName is a NameNormalizer removing directories and the extensions ".up.sql", ".down.sql" and ".sql".
+func BaseName(name string) string {
+	name = path.Base(filepath.ToSlash(name))
+	for _, suffix := range []string{upSuffix, downSuffix, ".sql"} {
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix)
+		}
+	}
+	return name
+}
+
+// selectExecuted selects columns of the rows of applied migrations.
+func (mM MigrationManager) selectExecuted(s selector, columns ...string) *dbr.SelectBuilder {
+	builder := mM.selectRows(s, columns...)
+	if mM.SoftDelete {
+		builder = builder.Where("rolled_back_at IS NULL")
+	}
+	return builder
+}
+
+// selectRows selects columns of all rows of the meta table, including rolled back ones.
+func (mM MigrationManager) selectRows(s selector, columns ...string) *dbr.SelectBuilder {
+	builder := s.Select(columns...).From(mM.table())
+	if "" != mM.PartitionColumn {
+		builder = builder.Where(mM.partitionEquals(), mM.PartitionValue)
+	}
+	return builder
+}
+
+// partitionEquals returns the condition comparing PartitionColumn with a placeholder.
+func (mM MigrationManager) partitionEquals() string {
+	return quoteIdentifier(mM.Dialect, mM.PartitionColumn) + " = ?"
+}
+
+// executedSet returns the names of all applied migrations.
+func (mM MigrationManager) executedSet(s selector) (map[string]bool, error) {
+	names, err := mM.selectExecuted(s, "name").ReturnStrings()
+	if nil != err {
+		return nil, err
+	}
+	executed := make(map[string]bool, len(names))
+	for _, name := range names {
+		executed[name] = true
+	}
+	return executed, nil
+}
+
+// runUp applies a single migration in its own transaction.
+func (mM MigrationManager) runUp(session *dbr.Session, migration Migration) (rErr error) {
+	defer func(start time.Time) {
+		mM.observe(migration, start, rErr)
+	}(time.Now())
+	transaction, monitor, err := mM.begin(session, mM.isolation(migration))
+	if nil != err {
+		return err
+	}
+	if err = mM.monitored(transaction, monitor, migration, "up", mM.ApplyUp); nil != err {
+		transaction.Rollback()
+		return err
+	}
+	if err = transaction.Commit(); nil != err {
+		transaction.Rollback()
+		return err
+	}
+	return nil
+}
+
+// isolation returns the isolation level to apply migration with.
+func (mM MigrationManager) isolation(migration Migration) sql.IsolationLevel {
+	if sql.LevelDefault != migration.Isolation {
+		return migration.Isolation
+	}
+	return mM.Isolation
+}
+
+// ApplyUp runs the Up of migration and marks it as executed within transaction, honoring Baseline, ShouldRun,
+// Precheck and Verify. It neither checks if the migration ran before nor begins, commits or rolls back transaction, the caller owns
+// its lifecycle and has to roll it back if an error is returned.
+func (mM MigrationManager) ApplyUp(transaction *dbr.Tx, migration Migration) error {
+	start := time.Now()
+	if migration.Baseline || "" != mM.executedReplaced(transaction, migration) {
+		return mM.markApplied(transaction, migration, 0)
+	}
+	if "" != migration.MinServerVersion {
+		if err := mM.checkServerVersion(transaction, migration); nil != err {
 			return err
 		}
-		if err2 := transaction.Commit(); nil != err2 {
-			transaction.Rollback()
-			return err2
+	}
+	if nil != migration.ShouldRun {
+		var run bool
+		err := callMigrate(func(transaction *dbr.Tx) (rErr error) {
+			run, rErr = migration.ShouldRun(transaction)
+			return
+		}, transaction, migration, "up")
+		if nil != err {
+			return err
+		}
+		if !run {
+			if migration.SkipWithoutMarking {
+				return nil
+			}
+			return mM.markApplied(transaction, migration, 0)
 		}
-	} else {
+	}
+	if nil != migration.Precheck {
+		err := callMigrate(migration.Precheck, transaction, migration, "precheck")
+		if errors.Is(err, ErrAlreadyApplied) {
+			return mM.markApplied(transaction, migration, 0)
+		}
+		if nil != err {
+			return err
+		}
+	}
+	up := migration.Up
+	if nil != migration.External {
+		up = migration.External.run
+	}
+	if err := callMigrate(up, transaction, migration, "up"); nil != err && !ignored(migration, err) {
+		return err
+	}
+	if 0 != migration.ExpectedAffected {
+		if err := checkAffected(transaction, migration); nil != err {
+			return err
+		}
+	}
+	if nil != migration.Verify {
+		if err := callMigrate(migration.Verify, transaction, migration, "verify"); nil != err {
+			return err
+		}
+	}
+	return mM.markApplied(transaction, migration, time.Since(start))
+}
+
+// ignored checks if the *MigrationError err wraps an error of Up that IgnoreErrors of migration accepts.
+func ignored(migration Migration, err error) bool {
+	var migrationErr *MigrationError
+	return nil != migration.IgnoreErrors && errors.As(err, &migrationErr) && migration.IgnoreErrors(migrationErr.Err)
+}
+
+// executedReplaced returns the first name replaced by migration that was executed, or "" if none was.
+func (mM MigrationManager) executedReplaced(s selector, migration Migration) string {
+	for _, replaced := range migration.Replaces {
+		if mM.checkIfExecuted(s, Migration{Name: replaced}) {
+			return replaced
+		}
+	}
+	return ""
+}
+
+// markApplied marks migration, which took duration to apply, and the migrations it replaces as executed.
+func (mM MigrationManager) markApplied(transaction *dbr.Tx, migration Migration, duration time.Duration) error {
+	for _, replaced := range migration.Replaces {
+		if mM.checkIfExecuted(transaction, Migration{Name: replaced}) {
+			continue
+		}
+		if err := mM.MarkAsExecuted(transaction, Migration{Name: replaced}); nil != err {
+			return err
+		}
+	}
+	return mM.markExecuted(transaction, []Migration{migration}, duration)
+}
+
+// applyDown runs the Down of migration and marks it as not executed within transaction.
+func (mM MigrationManager) applyDown(transaction *dbr.Tx, migration Migration) error {
+	if err := callMigrate(migration.Down, transaction, migration, "down"); nil != err {
+		return err
+	}
+	for _, replaced := range migration.Replaces {
+		if err := mM.MarkAsNotExecuted(transaction, Migration{Name: replaced}); nil != err {
+			return err
+		}
+	}
+	return mM.MarkAsNotExecuted(transaction, migration)
+}
+
+// callMigrate runs fn for migration, turning an error or a panic into a *MigrationError.
+func callMigrate(fn Migrate, transaction *dbr.Tx, migration Migration, direction string) (rErr error) {
+	defer func() {
+		if r := recover(); nil != r {
+			rErr = migrationError(migration, direction, errors.New(fmt.Sprintf("panic: %v", r)))
+		}
+	}()
+	if err := fn(transaction); nil != err {
+		return migrationError(migration, direction, err)
+	}
+	return nil
+}
+
+// RunSingleMigrationDown undos a migration if it was already applied, otherwise throws an error.
+func (mM MigrationManager) RunSingleMigrationDown(session *dbr.Session, migration Migration) error {
+	if err := mM.downAllowed(); nil != err {
+		return err
+	}
+	return mM.runDown(session, migration)
+}
+
+// runDown is RunSingleMigrationDown regardless of the Environment.
+func (mM MigrationManager) runDown(session *dbr.Session, migration Migration) error {
+	if !mM.checkIfExecuted(session, migration) {
+		return errors.New("migration was not yet executed")
+	}
+	transaction, monitor, err := mM.begin(session, mM.isolation(migration))
+	if nil != err {
+		return err
+	}
+	if err = mM.monitored(transaction, monitor, migration, "down", mM.applyDown); nil != err {
+		transaction.Rollback()
+		return err
+	}
+	if err = transaction.Commit(); nil != err {
 		transaction.Rollback()
+		return err
 	}
 	return nil
 }
@@ -0,0 +1,474 @@
+package gomigration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocraft/dbr"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// testConnection returns a connection to a new SQLite database that is removed after the test.
+func testConnection(t *testing.T) *dbr.Connection {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_busy_timeout=5000&_journal_mode=WAL")
+	if nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return dbr.NewConnection(db, nil)
+}
+
+// testManager returns an initialized SQLite MigrationManager on a new database.
+func testManager(t *testing.T) MigrationManager {
+	t.Helper()
+	return NewMigrationManager(testConnection(t), WithDialect(SQLite))
+}
+
+// noop is an Up or Down that does not change anything.
+func noop(*dbr.Tx) error {
+	return nil
+}
+
+// markExecuted records migrations as executed in their own transaction.
+func markExecuted(t *testing.T, mM MigrationManager, migrations ...Migration) {
+	t.Helper()
+	transaction, err := mM.Connection.NewSession(nil).Begin()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err = mM.MarkManyAsExecuted(transaction, migrations); nil != err {
+		transaction.Rollback()
+		t.Fatal(err)
+	}
+	if err = transaction.Commit(); nil != err {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckIfExecutedIsCaseSensitive(t *testing.T) {
+	mM := testManager(t)
+	markExecuted(t, mM, Migration{Name: "AddUsers"})
+	session := mM.Connection.NewSession(nil)
+	if !mM.CheckIfExecuted(session, Migration{Name: "AddUsers"}) {
+		t.Error("expected AddUsers to be executed")
+	}
+	if mM.CheckIfExecuted(session, Migration{Name: "addusers"}) {
+		t.Error("expected addusers not to be executed")
+	}
+}
+
+func TestNestedTransactionIsRejected(t *testing.T) {
+	mM := testManager(t)
+	migration := Migration{Name: "nested", Up: func(transaction *dbr.Tx) error {
+		nested, err := transaction.Session.Begin()
+		if nil != err {
+			return err
+		}
+		return nested.Rollback()
+	}}
+	session := mM.Connection.NewSession(nil)
+	if err := mM.RunSingleMigrationUp(session, migration); !errors.Is(err, ErrNestedTransaction) {
+		t.Fatalf("expected ErrNestedTransaction, got %v", err)
+	}
+	if mM.CheckIfExecuted(session, migration) {
+		t.Error("expected the migration not to be marked as executed")
+	}
+}
+
+func TestCommitEverySkipsExecutedMigrations(t *testing.T) {
+	mM := testManager(t)
+	mM.CommitEvery = 2
+	markExecuted(t, mM, Migration{Name: "first"})
+	var ran []string
+	up := func(name string) Migrate {
+		return func(*dbr.Tx) error {
+			ran = append(ran, name)
+			return nil
+		}
+	}
+	migrations := []Migration{{Name: "first", Up: up("first")}, {Name: "second", Up: up("second")}, {Name: "third", Up: up("third")}}
+	if err := mM.MigrationRunnerContext(context.Background(), migrations); nil != err {
+		t.Fatal(err)
+	}
+	if 2 != len(ran) || "second" != ran[0] || "third" != ran[1] {
+		t.Errorf("expected second and third to run, got %v", ran)
+	}
+}
+
+func TestPanicsAreWrappedInMigrationError(t *testing.T) {
+	mM := testManager(t)
+	session := mM.Connection.NewSession(nil)
+	migration := Migration{Name: "panicking", Source: "migrations/panicking.go:1", Up: func(*dbr.Tx) error {
+		panic("boom")
+	}, Down: func(*dbr.Tx) error {
+		panic("boom")
+	}}
+	var migrationErr *MigrationError
+	if err := mM.RunSingleMigrationUp(session, migration); !errors.As(err, &migrationErr) {
+		t.Fatalf("expected a *MigrationError, got %v", err)
+	}
+	if "up" != migrationErr.Direction || migration.Source != migrationErr.Source || !strings.Contains(migrationErr.Error(), "panic: boom") {
+		t.Errorf("unexpected error %v", migrationErr)
+	}
+	if mM.CheckIfExecuted(session, migration) {
+		t.Fatal("expected the panicking migration not to be marked as executed")
+	}
+	markExecuted(t, mM, migration)
+	if err := mM.RunSingleMigrationDown(session, migration); !errors.As(err, &migrationErr) || "down" != migrationErr.Direction {
+		t.Fatalf("expected a *MigrationError running down, got %v", err)
+	}
+	if !mM.CheckIfExecuted(session, migration) {
+		t.Error("expected the migration to stay executed")
+	}
+}
+
+func TestBaselineIsMarkedWithoutRunningUp(t *testing.T) {
+	mM := testManager(t)
+	ran := false
+	migration := Migration{Name: "existing", Baseline: true, Up: func(*dbr.Tx) error {
+		ran = true
+		return nil
+	}}
+	mM.MigrationRunner([]Migration{migration})
+	if ran {
+		t.Error("expected Up of the baseline not to run")
+	}
+	if !mM.CheckIfExecuted(mM.Connection.NewSession(nil), migration) {
+		t.Error("expected the baseline to be marked as executed")
+	}
+}
+
+func TestCheckIfSane(t *testing.T) {
+	mM := MigrationManager{}
+	tests := []struct {
+		migrations []Migration
+		valid      bool
+	}{
+		{[]Migration{{Name: "a"}, {Name: "b"}}, true},
+		{[]Migration{{Name: "a"}, {Name: "b"}, {Name: "a"}}, false},
+		{[]Migration{{Name: "b", Replaces: []string{"a"}}}, true},
+		{[]Migration{{Name: "a"}, {Name: "b", Replaces: []string{"a"}}}, false},
+		{[]Migration{{Name: "b", Replaces: []string{"a"}}, {Name: "c", Replaces: []string{"a"}}}, false},
+	}
+	for i, test := range tests {
+		if err := mM.CheckIfSane(test.migrations); test.valid != (nil == err) {
+			t.Errorf("case %d: expected valid %v, got %v", i, test.valid, err)
+		}
+	}
+}
+
+func TestReplacesOnFreshDatabase(t *testing.T) {
+	mM := testManager(t)
+	ran := false
+	fixed := Migration{Name: "fixed", Replaces: []string{"buggy", "older"}, Up: func(*dbr.Tx) error {
+		ran = true
+		return nil
+	}}
+	if err := mM.MigrationRunnerContext(context.Background(), []Migration{fixed}); nil != err {
+		t.Fatal(err)
+	}
+	if !ran {
+		t.Error("expected Up of the replacement to run")
+	}
+	executed, err := mM.CheckIfExecutedBatch(mM.Connection.NewSession(nil), []Migration{fixed, {Name: "buggy"}, {Name: "older"}})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !executed["fixed"] || !executed["buggy"] || !executed["older"] {
+		t.Errorf("expected the replacement and the replaced names to be marked, got %v", executed)
+	}
+}
+
+func TestReplacesAfterOldVersionRan(t *testing.T) {
+	mM := testManager(t)
+	markExecuted(t, mM, Migration{Name: "buggy"})
+	ran := false
+	fixed := Migration{Name: "fixed", Replaces: []string{"buggy"}, Up: func(*dbr.Tx) error {
+		ran = true
+		return nil
+	}}
+	if err := mM.MigrationRunnerContext(context.Background(), []Migration{fixed}); nil != err {
+		t.Fatal(err)
+	}
+	if ran {
+		t.Error("expected Up of the replacement not to run")
+	}
+	executed, err := mM.ListExecuted(mM.Connection.NewSession(nil))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 2 != len(executed) || "buggy" != executed[0].Name || "fixed" != executed[1].Name {
+		t.Errorf("expected the replacement to be marked next to the old version, got %v", executed)
+	}
+}
+
+func TestRunnerRejectsDuplicateNames(t *testing.T) {
+	mM := testManager(t)
+	ran := false
+	up := func(*dbr.Tx) error {
+		ran = true
+		return nil
+	}
+	if err := mM.MigrationRunnerContext(context.Background(), []Migration{{Name: "a", Up: up}, {Name: "a", Up: up}}); nil == err {
+		t.Fatal("expected the duplicate name to be rejected")
+	}
+	if ran {
+		t.Error("expected no migration to run")
+	}
+}
+
+func TestCheckIfSaneComparesNormalizedNames(t *testing.T) {
+	mM := MigrationManager{NameNormalizer: BaseName}
+	if err := mM.CheckIfSane([]Migration{{Name: "a/users.up.sql"}, {Name: "b/users.up.sql"}}); nil == err {
+		t.Error("expected names normalized to the same name to be rejected")
+	}
+	if err := mM.CheckIfSane([]Migration{{Name: "users.up.sql"}, {Name: "fix", Replaces: []string{"users"}}}); nil == err {
+		t.Error("expected a replaced name normalized to a listed migration to be rejected")
+	}
+}
+
+func TestFailingVerifyRollsBack(t *testing.T) {
+	mM := testManager(t)
+	session := mM.Connection.NewSession(nil)
+	migration := Migration{Name: "users", Up: func(transaction *dbr.Tx) error {
+		_, err := transaction.Exec("CREATE TABLE users (id INTEGER)")
+		return err
+	}, Verify: func(transaction *dbr.Tx) error {
+		_, err := transaction.Select("count(*)").From("users").Where("id > 0").ReturnInt64()
+		if nil != err {
+			return err
+		}
+		return errors.New("no users")
+	}}
+	if err := mM.RunSingleMigrationUp(session, migration); nil == err {
+		t.Fatal("expected the failing Verify to fail the migration")
+	}
+	if mM.CheckIfExecuted(session, migration) {
+		t.Error("expected the migration not to be marked as executed")
+	}
+	if _, err := session.Select("count(*)").From("users").ReturnInt64(); nil == err {
+		t.Error("expected the table created by Up to be rolled back")
+	}
+	migration.Verify = func(transaction *dbr.Tx) error {
+		_, err := transaction.Select("count(*)").From("users").ReturnInt64()
+		return err
+	}
+	if err := mM.RunSingleMigrationUp(session, migration); nil != err {
+		t.Fatal(err)
+	}
+	if !mM.CheckIfExecuted(session, migration) {
+		t.Error("expected the verified migration to be marked as executed")
+	}
+}
+
+func TestMarkManyAsExecuted(t *testing.T) {
+	mM := testManager(t)
+	session := mM.Connection.NewSession(nil)
+	migrations := make([]Migration, 0, 100)
+	for i := 0; i < 100; i++ {
+		migrations = append(migrations, Migration{Name: fmt.Sprintf("%03d_baseline", i)})
+	}
+	for _, commit := range []bool{false, true} {
+		transaction, err := session.Begin()
+		if nil != err {
+			t.Fatal(err)
+		}
+		if err = mM.MarkManyAsExecuted(transaction, migrations); nil != err {
+			transaction.Rollback()
+			t.Fatal(err)
+		}
+		expected := 0
+		if commit {
+			expected = 100
+			err = transaction.Commit()
+		} else {
+			err = transaction.Rollback()
+		}
+		if nil != err {
+			t.Fatal(err)
+		}
+		if executed, _ := mM.ListExecuted(session); expected != len(executed) {
+			t.Errorf("commit %v: expected %d recorded migrations, got %d", commit, expected, len(executed))
+		}
+	}
+}
+
+func TestMarkManyAsExecutedRejectsOversizedMeta(t *testing.T) {
+	mM := testManager(t)
+	session := mM.Connection.NewSession(nil)
+	transaction, err := session.Begin()
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer transaction.Rollback()
+	huge := map[string]string{"notes": strings.Repeat("x", maxMetaSize)}
+	if err = mM.MarkManyAsExecuted(transaction, []Migration{{Name: "a"}, {Name: "b", Meta: huge}}); nil == err {
+		t.Fatal("expected the oversized meta to be rejected")
+	}
+	if executed, _ := mM.listExecuted(transaction); 0 != len(executed) {
+		t.Errorf("expected no migration to be recorded, got %v", executed)
+	}
+}
+
+func TestRunnerInitializesHandBuiltManager(t *testing.T) {
+	mM := MigrationManager{Connection: testConnection(t), Dialect: SQLite}
+	session := mM.Connection.NewSession(nil)
+	if initialized, err := mM.IsInitialized(session); nil != err || initialized {
+		t.Fatalf("expected no meta table yet, got %v, %v", initialized, err)
+	}
+	mM.MigrationRunner([]Migration{{Name: "first", Up: noop}})
+	if !mM.CheckIfExecuted(session, Migration{Name: "first"}) {
+		t.Error("expected the migration to be executed")
+	}
+}
+
+func TestIgnoreErrors(t *testing.T) {
+	mM := testManager(t)
+	session := mM.Connection.NewSession(nil)
+	exists := errors.New("table already exists")
+	ignore := func(err error) bool {
+		return exists == err
+	}
+	ignored := Migration{Name: "ignored", IgnoreErrors: ignore, Up: func(*dbr.Tx) error {
+		return exists
+	}}
+	if err := mM.RunSingleMigrationUp(session, ignored); nil != err {
+		t.Fatalf("expected the error to be ignored, got %v", err)
+	}
+	if !mM.CheckIfExecuted(session, ignored) {
+		t.Error("expected the migration with an ignored error to be marked as executed")
+	}
+	failing := Migration{Name: "failing", IgnoreErrors: ignore, Up: func(*dbr.Tx) error {
+		return errors.New("syntax error")
+	}}
+	if err := mM.RunSingleMigrationUp(session, failing); nil == err {
+		t.Fatal("expected other errors to fail the migration")
+	}
+	if mM.CheckIfExecuted(session, failing) {
+		t.Error("expected the failed migration not to be marked as executed")
+	}
+}
+
+func TestModulesAreIndependent(t *testing.T) {
+	connection := testConnection(t)
+	billing := NewModuleMigrationManager(connection, "billing", WithDialect(SQLite))
+	shipping := NewModuleMigrationManager(connection, "shipping", WithDialect(SQLite))
+	if "dbMigrations_billing" != billing.TableName() || billing.LockKey() == shipping.LockKey() {
+		t.Fatalf("expected separate tables and locks, got %s and %s", billing.TableName(), billing.LockKey())
+	}
+	billing.MigrationRunner([]Migration{{Name: "init", Up: noop}})
+	session := connection.NewSession(nil)
+	if shipping.CheckIfExecuted(session, Migration{Name: "init"}) {
+		t.Fatal("expected the migration of billing not to count for shipping")
+	}
+	shipping.MigrationRunner([]Migration{{Name: "init", Up: noop}})
+	if !shipping.CheckIfExecuted(session, Migration{Name: "init"}) {
+		t.Error("expected shipping to apply its migration of the same name")
+	}
+}
+
+func TestPrecheck(t *testing.T) {
+	mM := testManager(t)
+	session := mM.Connection.NewSession(nil)
+	ran := false
+	up := func(*dbr.Tx) error {
+		ran = true
+		return nil
+	}
+	applied := Migration{Name: "applied", Up: up, Precheck: func(*dbr.Tx) error {
+		return ErrAlreadyApplied
+	}}
+	if err := mM.RunSingleMigrationUp(session, applied); nil != err {
+		t.Fatal(err)
+	}
+	if ran || !mM.CheckIfExecuted(session, applied) {
+		t.Error("expected the applied migration to be marked without running Up")
+	}
+	partial := Migration{Name: "partial", Up: up, Precheck: func(*dbr.Tx) error {
+		return errors.New("index exists but column is missing")
+	}}
+	if err := mM.RunSingleMigrationUp(session, partial); nil == err {
+		t.Fatal("expected the failing precheck to fail the migration")
+	}
+	if ran || mM.CheckIfExecuted(session, partial) {
+		t.Error("expected the partial migration to fail without running Up")
+	}
+}
+
+// groupMigrations returns a group of two migrations whose second fails, recording the Downs run in undone.
+func groupMigrations(undone *[]string) []Migration {
+	return []Migration{
+		{Name: "first", Group: "accounts", Up: noop, Down: func(*dbr.Tx) error {
+			*undone = append(*undone, "first")
+			return nil
+		}},
+		{Name: "second", Group: "accounts", Up: func(*dbr.Tx) error {
+			return errors.New("failed")
+		}, Down: noop},
+	}
+}
+
+func TestFailingGroupIsRolledBack(t *testing.T) {
+	mM := testManager(t)
+	var undone []string
+	migrations := groupMigrations(&undone)
+	if err := mM.MigrationRunnerContext(context.Background(), migrations); nil == err || !strings.Contains(err.Error(), "was rolled back") {
+		t.Fatalf("expected the group to be rolled back, got %v", err)
+	}
+	if 1 != len(undone) || mM.CheckIfExecuted(mM.Connection.NewSession(nil), migrations[0]) {
+		t.Errorf("expected the first migration to be undone, got %v", undone)
+	}
+}
+
+func TestFailingGroupIsKeptInProduction(t *testing.T) {
+	mM := testManager(t)
+	mM.Environment = ProductionEnvironment
+	var undone []string
+	migrations := groupMigrations(&undone)
+	if err := mM.MigrationRunnerContext(context.Background(), migrations); nil == err || !strings.Contains(err.Error(), "was not rolled back") {
+		t.Fatalf("expected the group not to be rolled back, got %v", err)
+	}
+	if 0 != len(undone) || !mM.CheckIfExecuted(mM.Connection.NewSession(nil), migrations[0]) {
+		t.Errorf("expected the first migration to stay applied, got %v", undone)
+	}
+}
+
+func TestDurationsAreOptIn(t *testing.T) {
+	connection := testConnection(t)
+	if _, err := connection.Db.Exec("CREATE TABLE dbMigrations (id INTEGER PRIMARY KEY AUTOINCREMENT, name VARCHAR(255) NOT NULL, execution DATETIME NOT NULL)"); nil != err {
+		t.Fatal(err)
+	}
+	mM := NewMigrationManager(connection, WithDialect(SQLite))
+	migration := Migration{Name: "baseline", Up: noop, Down: noop}
+	if err := mM.MigrationRunnerContext(context.Background(), []Migration{migration}); nil != err {
+		t.Fatalf("expected a meta table without duration_ms to work, got %v", err)
+	}
+	if !mM.CheckIfExecuted(mM.Connection.NewSession(nil), migration) {
+		t.Error("expected the migration to be marked as executed")
+	}
+}
+
+func TestRecordDurations(t *testing.T) {
+	mM := NewMigrationManager(testConnection(t), WithDialect(SQLite), WithRecordDurations())
+	migration := Migration{Name: "slow", Up: func(*dbr.Tx) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}}
+	if err := mM.MigrationRunnerContext(context.Background(), []Migration{migration}); nil != err {
+		t.Fatal(err)
+	}
+	history, err := mM.History(mM.Connection.NewSession(nil))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 1 != len(history) || 0 == history[0].Duration {
+		t.Errorf("expected the duration to be recorded, got %+v", history)
+	}
+}
@@ -0,0 +1,88 @@
+package gomigration
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gocraft/dbr"
+)
+
+// HistoryEntry is a single event in the life of a migration.
+type HistoryEntry struct {
+	Name string
+	// Direction is "up" for applying and "down" for rolling back the migration.
+	Direction string
+	Time      time.Time
+	// Duration is how long applying took, zero for rollbacks and rows written without RecordDurations.
+	Duration time.Duration
+	// DeploymentID is the DeploymentID of the MigrationManager that applied the migration, if it had one.
+	DeploymentID string
+}
+
+// historyRow is a row of the meta table including rolled back ones.
+type historyRow struct {
+	Name         string         `db:"name"`
+	Execution    string         `db:"execution"`
+	RolledBackAt dbr.NullString `db:"rolled_back_at"`
+	DurationMs   dbr.NullInt64  `db:"duration_ms"`
+	DeploymentID dbr.NullString `db:"deployment_id"`
+}
+
+// History returns every recorded event in chronological order, ties ordered by OrderBy and applying before rolling back.
+// With SoftDelete the rows of rolled back migrations are kept, so the history shows every up and down. Otherwise
+// rolling back deletes the row and only the ups of the currently applied migrations remain.
+// Durations are only recorded with RecordDurations.
+func (mM MigrationManager) History(session *dbr.Session) ([]HistoryEntry, error) {
+	if err := mM.checkOrdering(); nil != err {
+		return nil, err
+	}
+	var rows []historyRow
+	if _, err := mM.ordered(mM.selectRows(mM.readSession(session), "*")).LoadStructs(&rows); nil != err {
+		return nil, err
+	}
+	type event struct {
+		HistoryEntry
+		row int
+	}
+	events := make([]event, 0, len(rows))
+	for i, row := range rows {
+		execution, _ := parseExecution(row.Execution)
+		entry := HistoryEntry{Name: row.Name, Direction: "up", Time: execution, DeploymentID: row.DeploymentID.String}
+		if row.DurationMs.Valid {
+			entry.Duration = time.Duration(row.DurationMs.Int64) * time.Millisecond
+		}
+		events = append(events, event{HistoryEntry: entry, row: i})
+		if row.RolledBackAt.Valid && "" != row.RolledBackAt.String {
+			rolledBack, _ := parseExecution(row.RolledBackAt.String)
+			down := HistoryEntry{Name: row.Name, Direction: "down", Time: rolledBack, DeploymentID: row.DeploymentID.String}
+			events = append(events, event{HistoryEntry: down, row: i})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Time.Equal(events[j].Time) {
+			return events[i].Time.Before(events[j].Time)
+		}
+		return events[i].row < events[j].row
+	})
+	history := make([]HistoryEntry, 0, len(events))
+	for _, e := range events {
+		history = append(history, e.HistoryEntry)
+	}
+	return history, nil
+}
+
+// DeploymentHistory returns the entries of History applied by a MigrationManager with the given DeploymentID, answering
+// what that deployment changed. The rollback of such a migration is included, whoever rolled it back.
+func (mM MigrationManager) DeploymentHistory(session *dbr.Session, deploymentID string) ([]HistoryEntry, error) {
+	history, err := mM.History(session)
+	if nil != err {
+		return nil, err
+	}
+	var entries []HistoryEntry
+	for _, entry := range history {
+		if deploymentID == entry.DeploymentID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
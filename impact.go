@@ -0,0 +1,189 @@
+package gomigration
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TableImpact is a single operation of a statement on a table.
+type TableImpact struct {
+	// Operation is the leading keyword of the statement, like CREATE, ALTER, DROP, TRUNCATE, INSERT, UPDATE or DELETE.
+	Operation string
+	// Table is the affected table, empty if it could not be recognized.
+	Table string
+	// High is set for statements that may destroy data, see destroysData.
+	High bool
+}
+
+// Impact is the estimated impact of the Up of a single migration.
+type Impact struct {
+	Name   string
+	Tables []TableImpact
+	// High is set if any of Tables is.
+	High bool
+	// Opaque is set for migrations built from Go functions, whose sql can not be analyzed.
+	Opaque bool
+}
+
+// EstimateImpact lists the tables the statements of every migration's Up change and how, for a quick risk summary like
+// "this deploy drops table X". It is a heuristic based on the text of the statements of sql files, nothing is
+// executed, and migrations built from Go functions are reported as Opaque.
+func EstimateImpact(migrations []Migration) ([]Impact, error) {
+	impacts := make([]Impact, 0, len(migrations))
+	for _, migration := range migrations {
+		preview, err := previewSQL(migration)
+		if nil != err {
+			return nil, err
+		}
+		impact := Impact{Name: migration.Name, Opaque: preview.Opaque}
+		for _, statement := range preview.Statements {
+			for _, table := range statementImpact(statement) {
+				impact.Tables = append(impact.Tables, table)
+				impact.High = impact.High || table.High
+			}
+		}
+		impacts = append(impacts, impact)
+	}
+	return impacts, nil
+}
+
+// IsDestructiveDown reports if the Down of migration may destroy data, RollbackN and RollbackAll ask Confirm before
+// rolling back such migrations. It is a best-effort static analysis of the down file of migrations loaded from sql files, flagging the same
+// statements EstimateImpact rates as high impact. Migrations without down file, whose Down can not be analysed, and
+// files that can not be read count as destructive.
+func IsDestructiveDown(migration Migration) bool {
+	if "" == migration.downFile {
+		return true
+	}
+	statements, err := readStatements(migration.downFile)
+	if nil != err {
+		return true
+	}
+	for _, statement := range statements {
+		if destroysData(statementWords(statement)) {
+			return true
+		}
+	}
+	return false
+}
+
+// statementWords splits a statement into its words, separating them at parentheses and commas as well.
+func statementWords(statement string) []string {
+	return strings.FieldsFunc(statement, func(r rune) bool {
+		return unicode.IsSpace(r) || '(' == r || ')' == r || ',' == r
+	})
+}
+
+// destroysData reports if the statement split into words may destroy data: DROP, TRUNCATE and DELETE statements as
+// well as ALTER statements dropping something.
+func destroysData(words []string) bool {
+	if 0 == len(words) {
+		return false
+	}
+	switch strings.ToUpper(words[0]) {
+	case "DROP", "TRUNCATE", "DELETE":
+		return true
+	case "ALTER":
+		return 0 <= indexWord(words, "DROP")
+	}
+	return false
+}
+
+// statementImpact recognizes the tables a single statement changes.
+func statementImpact(statement string) []TableImpact {
+	words := statementWords(statement)
+	if 0 == len(words) {
+		return nil
+	}
+	operation := strings.ToUpper(words[0])
+	high := destroysData(words)
+	var tables []string
+	switch operation {
+	case "CREATE":
+		if i := indexWord(words, "ON"); 0 <= i && 0 > indexWord(words[:i], "TABLE") {
+			tables = wordAfter(words, i)
+		} else {
+			tables = wordAfter(words, skipWords(words, indexWord(words, "TABLE"), "IF", "NOT", "EXISTS"))
+		}
+	case "ALTER", "TRUNCATE":
+		tables = wordAfter(words, indexWordOr(words, "TABLE", 0))
+	case "DROP":
+		if i := indexWord(words, "ON"); 0 <= i && 0 > indexWord(words[:i], "TABLE") {
+			tables = wordAfter(words, i)
+		} else if i := indexWord(words, "TABLE"); 0 <= i {
+			tables = identifiers(words[skipWords(words, i, "IF", "EXISTS")+1:])
+		}
+	case "INSERT", "REPLACE":
+		tables = wordAfter(words, indexWord(words, "INTO"))
+	case "UPDATE":
+		tables = wordAfter(words, skipWords(words, 0, "LOW_PRIORITY", "IGNORE"))
+	case "DELETE":
+		tables = wordAfter(words, indexWord(words, "FROM"))
+	case "RENAME":
+		if 3 < len(words) {
+			tables = []string{identifier(words[2]), identifier(words[len(words)-1])}
+		}
+	}
+	if 0 == len(tables) {
+		return []TableImpact{{Operation: operation, High: high}}
+	}
+	impacts := make([]TableImpact, 0, len(tables))
+	for _, table := range tables {
+		impacts = append(impacts, TableImpact{Operation: operation, Table: table, High: high})
+	}
+	return impacts
+}
+
+// indexWord returns the position of the first word equal to keyword ignoring case, or -1.
+func indexWord(words []string, keyword string) int {
+	for i, word := range words {
+		if strings.EqualFold(word, keyword) {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexWordOr(words []string, keyword string, fallback int) int {
+	if i := indexWord(words, keyword); 0 <= i {
+		return i
+	}
+	return fallback
+}
+
+// skipWords moves from position i past all following words that are one of keywords.
+func skipWords(words []string, i int, keywords ...string) int {
+	if 0 > i {
+		return i
+	}
+	for i+1 < len(words) && 0 <= indexWord(keywords, words[i+1]) {
+		i++
+	}
+	return i
+}
+
+// wordAfter returns the identifier following position i, if there is one.
+func wordAfter(words []string, i int) []string {
+	if 0 > i || i+1 >= len(words) {
+		return nil
+	}
+	return []string{identifier(words[i+1])}
+}
+
+// identifiers returns the table names of a list like "a, b CASCADE".
+func identifiers(words []string) []string {
+	var tables []string
+	for _, word := range words {
+		switch strings.ToUpper(word) {
+		case "CASCADE", "RESTRICT":
+			continue
+		}
+		tables = append(tables, identifier(word))
+	}
+	return tables
+}
+
+// identifier removes the quotes of a table name.
+func identifier(word string) string {
+	return strings.Trim(strings.TrimSuffix(word, ";"), "`\"[]")
+}
@@ -0,0 +1,48 @@
+package gomigration
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStatementImpact(t *testing.T) {
+	tests := map[string][]TableImpact{
+		"CREATE TABLE IF NOT EXISTS `users` (id INT)": {{"CREATE", "users", false}},
+		"CREATE UNIQUE INDEX idx ON users (name)":     {{"CREATE", "users", false}},
+		"ALTER TABLE orders ADD x INT":                {{"ALTER", "orders", false}},
+		"ALTER TABLE orders DROP COLUMN x":            {{"ALTER", "orders", true}},
+		"DROP TABLE IF EXISTS a, b":                   {{"DROP", "a", true}, {"DROP", "b", true}},
+		"TRUNCATE TABLE logs":                         {{"TRUNCATE", "logs", true}},
+		"INSERT IGNORE INTO t (a) VALUES (1)":         {{"INSERT", "t", false}},
+		"UPDATE t SET a = 1":                          {{"UPDATE", "t", false}},
+		"DELETE FROM t WHERE a = 1":                   {{"DELETE", "t", true}},
+		"SET NAMES utf8":                              {{"SET", "", false}},
+	}
+	for statement, expected := range tests {
+		if actual := statementImpact(statement); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("%s: expected %+v, got %+v", statement, expected, actual)
+		}
+	}
+}
+
+func TestIsDestructiveDown(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.up.sql":   "SELECT 1;",
+		"a.down.sql": "ALTER TABLE x ADD y INT;",
+		"b.up.sql":   "SELECT 1;",
+		"b.down.sql": "UPDATE x SET y = 1; alter table x drop column y;",
+	})
+	migrations, err := LoadFromDir(dir)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if IsDestructiveDown(migrations[0]) {
+		t.Error("expected a down adding a column not to be destructive")
+	}
+	if !IsDestructiveDown(migrations[1]) {
+		t.Error("expected a down dropping a column to be destructive")
+	}
+	if !IsDestructiveDown(Migration{Name: "c"}) {
+		t.Error("expected a migration without down file to be destructive")
+	}
+}
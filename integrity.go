@@ -0,0 +1,131 @@
+package gomigration
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gocraft/dbr"
+)
+
+// metaRow is a row of the meta table whose columns may hold anything, including NULL.
+type metaRow struct {
+	ID        dbr.NullString `db:"id"`
+	Name      dbr.NullString `db:"name"`
+	Execution dbr.NullString `db:"execution"`
+}
+
+// loadMetaRows returns the id, name and execution of all rows of the meta table counting as executed.
+func (mM MigrationManager) loadMetaRows(s selector) ([]metaRow, error) {
+	var rows []metaRow
+	builder := mM.selectExecuted(s, "id", "name", "execution")
+	if KeyUUID == mM.Keys {
+		builder = builder.OrderBy("execution")
+	}
+	_, err := builder.OrderBy("id").LoadStructs(&rows)
+	return rows, err
+}
+
+// CheckIntegrity scans the meta table for anomalies and returns them as human-readable problems, none if it is fine:
+// names that are NULL, empty or recorded more than once, execution times that are missing or can not be parsed and
+// names that are not in the canonical form of the NameNormalizer. It does not change anything, see Repair.
+func (mM MigrationManager) CheckIntegrity(session *dbr.Session) ([]string, error) {
+	rows, err := mM.loadMetaRows(session)
+	if nil != err {
+		return nil, err
+	}
+	var problems []string
+	counts := make(map[string]int)
+	for _, row := range rows {
+		if !row.Name.Valid || "" == row.Name.String {
+			problems = append(problems, fmt.Sprintf("row %s has no name", row.ID.String))
+			continue
+		}
+		counts[row.Name.String]++
+		if _, err := parseExecution(row.Execution.String); !row.Execution.Valid || nil != err {
+			problems = append(problems, fmt.Sprintf("migration \"%s\" has the invalid execution time \"%s\"", row.Name.String, row.Execution.String))
+		}
+		if normalized := mM.normalize(row.Name.String); normalized != row.Name.String {
+			problems = append(problems, fmt.Sprintf("migration \"%s\" is not normalized, expected \"%s\"", row.Name.String, normalized))
+		}
+	}
+	var duplicates []string
+	for name, count := range counts {
+		if 1 < count {
+			duplicates = append(duplicates, fmt.Sprintf("migration \"%s\" is recorded %d times", name, count))
+		}
+	}
+	sort.Strings(duplicates)
+	return append(problems, duplicates...), nil
+}
+
+// RepairOptions selects the fixes Repair applies.
+type RepairOptions struct {
+	// Deduplicate deletes all but the earliest row of names recorded more than once.
+	Deduplicate bool
+	// Normalize rewrites names to their canonical form according to the NameNormalizer.
+	Normalize bool
+	// BackfillExecution sets missing or unparseable execution times to the current time. It is the only column that is
+	// backfilled, since the ordering and the status of migrations depend on it. The optional columns added later, like
+	// meta, checksum, version, deployment_id and duration_ms, are NULL for rows written before they existed, which all
+	// their readers treat as unknown, while any value filled in would be made up.
+	BackfillExecution bool
+}
+
+// RepairReport lists what Repair changed.
+type RepairReport struct {
+	Changes []string
+}
+
+// Repair fixes the problems of the meta table selected by options, as found by CheckIntegrity, within one transaction
+// so a failing repair leaves the table unchanged. Rows without name are never touched. Names are normalized before
+// duplicates are looked for, so rows only differing before normalization are deduplicated as well.
+func (mM MigrationManager) Repair(session *dbr.Session, options RepairOptions) (RepairReport, error) {
+	var report RepairReport
+	transaction, err := session.Begin()
+	if nil != err {
+		return report, err
+	}
+	defer transaction.RollbackUnlessCommitted()
+	rows, err := mM.loadMetaRows(transaction)
+	if nil != err {
+		return report, err
+	}
+	var changes []string
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		if !row.Name.Valid || "" == row.Name.String {
+			continue
+		}
+		name := row.Name.String
+		if options.Normalize {
+			name = mM.normalize(name)
+		}
+		if seen[name] && options.Deduplicate {
+			if _, err = transaction.DeleteFrom(mM.table()).Where("id = ?", row.ID.String).Exec(); nil != err {
+				return report, err
+			}
+			changes = append(changes, fmt.Sprintf("deleted duplicate row %s of migration \"%s\"", row.ID.String, row.Name.String))
+			continue
+		}
+		seen[name] = true
+		if name != row.Name.String {
+			if _, err = transaction.Update(mM.table()).Set("name", name).Where("id = ?", row.ID.String).Exec(); nil != err {
+				return report, err
+			}
+			changes = append(changes, fmt.Sprintf("renamed migration \"%s\" to \"%s\"", row.Name.String, name))
+		}
+		if _, err := parseExecution(row.Execution.String); options.BackfillExecution && (!row.Execution.Valid || nil != err) {
+			t := time.Now().Format(timeFormat)
+			if _, err = transaction.Update(mM.table()).Set("execution", t).Where("id = ?", row.ID.String).Exec(); nil != err {
+				return report, err
+			}
+			changes = append(changes, fmt.Sprintf("set execution time of migration \"%s\" to %s", name, t))
+		}
+	}
+	if err = transaction.Commit(); nil != err {
+		return report, err
+	}
+	report.Changes = changes
+	return report, nil
+}
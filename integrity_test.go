@@ -0,0 +1,25 @@
+package gomigration
+
+import (
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+	mM := testManager(t)
+	session := mM.Connection.NewSession(nil)
+	markExecuted(t, mM, Migration{Name: "first"}, Migration{Name: "first"}, Migration{Name: "second"})
+	if _, err := session.Update(mM.TableName()).Set("execution", nil).Where("name = ?", "second").Exec(); nil != err {
+		t.Fatal(err)
+	}
+	problems, err := mM.CheckIntegrity(session)
+	if nil != err || 2 != len(problems) {
+		t.Fatalf("expected two problems, got %v, %v", problems, err)
+	}
+	report, err := mM.Repair(session, RepairOptions{Deduplicate: true, BackfillExecution: true})
+	if nil != err || 2 != len(report.Changes) {
+		t.Fatalf("expected two changes, got %v, %v", report.Changes, err)
+	}
+	if problems, err = mM.CheckIntegrity(session); nil != err || 0 != len(problems) {
+		t.Errorf("expected no problems after the repair, got %v, %v", problems, err)
+	}
+}
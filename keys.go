@@ -0,0 +1,28 @@
+package gomigration
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// KeyStrategy selects the type of the id column of the meta table.
+type KeyStrategy int
+
+const (
+	// KeyAutoIncrement uses an integer id generated by the database. It is the default.
+	KeyAutoIncrement KeyStrategy = iota
+	// KeyUUID uses a random UUID generated by the MigrationManager, stored as CHAR(36) or as UUID on Postgres.
+	// Such ids do not tell the order migrations were applied in, so the execution time is used instead.
+	KeyUUID
+)
+
+// newUUID returns a random version 4 UUID.
+func newUUID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); nil != err {
+		return "", err
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+}
@@ -0,0 +1,21 @@
+package gomigration
+
+import (
+	"testing"
+)
+
+func TestWithKeysCreatesUUIDs(t *testing.T) {
+	mM := NewMigrationManager(testConnection(t), WithDialect(SQLite), WithKeys(KeyUUID), WithSchemaCheck(SchemaCheckTypes))
+	markExecuted(t, mM, Migration{Name: "first"})
+	session := mM.Connection.NewSession(nil)
+	var ids []string
+	if _, err := session.Select("id").From(mM.TableName()).LoadValues(&ids); nil != err {
+		t.Fatal(err)
+	}
+	if 1 != len(ids) || 36 != len(ids[0]) {
+		t.Errorf("expected a single UUID id, got %v", ids)
+	}
+	if err := mM.CheckSchema(); nil != err {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,526 @@
+package gomigration
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocraft/dbr"
+)
+
+const (
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+
+	// DefaultStreamThreshold is the file size in bytes above which a sql file is streamed from disk.
+	DefaultStreamThreshold = 16 << 20
+)
+
+// FileLoader builds migrations out of plain sql files.
+type FileLoader struct {
+	// StreamThreshold is the size in bytes above which a file is not kept in memory but read and executed
+	// statement by statement every time the migration runs. Zero or less streams every file.
+	StreamThreshold int64
+	// AllowMissingDown loads an up file without down file as an Irreversible migration whose Down returns
+	// ErrIrreversible. Otherwise a missing down file fails the load.
+	AllowMissingDown bool
+	// Params are bound to the placeholders ":<name>" in the statements of the files when they are executed, e.g. a
+	// configured default value, which is safe against injection unlike putting the value into the sql. Only values
+	// can be bound, identifiers like table names can not, see PrefixPlaceholder for those. A statement using a
+	// placeholder must not contain a literal "?" outside of quotes, as the driver would take it for a placeholder too.
+	Params map[string]interface{}
+}
+
+// NewFileLoader returns a FileLoader with the default settings.
+func NewFileLoader() FileLoader {
+	return FileLoader{StreamThreshold: DefaultStreamThreshold}
+}
+
+// LoadFromDir loads the migrations of dir using the default FileLoader.
+func LoadFromDir(dir string) ([]Migration, error) {
+	return NewFileLoader().LoadFromDir(dir)
+}
+
+// LoadFromDir creates a migration for every "<name>.up.sql" file in dir, using "<name>.down.sql" as its counterpart.
+// The migrations are ordered by file name, so names should start with a sortable prefix like "001_".
+func (l FileLoader) LoadFromDir(dir string) ([]Migration, error) {
+	upFiles, err := filepath.Glob(filepath.Join(dir, "*"+upSuffix))
+	if nil != err {
+		return nil, err
+	}
+	sort.Strings(upFiles)
+	migrations := make([]Migration, 0, len(upFiles))
+	for _, upFile := range upFiles {
+		migration, err := l.LoadFile(upFile)
+		if nil != err {
+			return nil, err
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
+
+// LoadFile creates a migration named "<name>" out of the file upFile called "<name>.up.sql" and the file
+// "<name>.down.sql" next to it.
+func (l FileLoader) LoadFile(upFile string) (Migration, error) {
+	if !strings.HasSuffix(upFile, upSuffix) {
+		return Migration{}, errors.New(fmt.Sprintf("migration file \"%s\" does not end with \"%s\"", upFile, upSuffix))
+	}
+	name := strings.TrimSuffix(filepath.Base(upFile), upSuffix)
+	return l.load(name, upFile, filepath.Join(filepath.Dir(upFile), name+downSuffix))
+}
+
+// load creates a migration called name out of upFile and downFile.
+func (l FileLoader) load(name, upFile, downFile string) (Migration, error) {
+	up, err := l.fileMigrate(upFile)
+	if nil != err {
+		return Migration{}, err
+	}
+	checksum, err := fileChecksum(upFile)
+	if nil != err {
+		return Migration{}, err
+	}
+	if _, err := os.Stat(downFile); os.IsNotExist(err) && l.AllowMissingDown {
+		return Migration{Name: name, Up: up, Down: irreversible, Irreversible: true, Checksum: checksum, Source: upFile, upFile: upFile}, nil
+	}
+	down, err := l.fileMigrate(downFile)
+	if nil != err {
+		return Migration{}, err
+	}
+	return Migration{Name: name, Up: up, Down: down, Checksum: checksum, Source: upFile, upFile: upFile, downFile: downFile}, nil
+}
+
+// fileChecksum returns the hex encoded SHA-256 of the raw content of the file at path.
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if nil != err {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); nil != err {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// LoadFromDirs loads the migrations of several directories using the default FileLoader.
+func LoadFromDirs(dirs ...string) ([]Migration, error) {
+	return NewFileLoader().LoadFromDirs(dirs...)
+}
+
+// LoadFromDirs loads the migrations of all dirs as a single list ordered by the prefix of their names, the part before
+// the first "_". That way numbered or timestamped files kept in several directories form one sequence.
+// A prefix used in more than one directory is an error, ties within a directory are ordered by name.
+func (l FileLoader) LoadFromDirs(dirs ...string) ([]Migration, error) {
+	type entry struct {
+		migration Migration
+		prefix    string
+	}
+	var entries []entry
+	owners := make(map[string]string)
+	for _, dir := range dirs {
+		migrations, err := l.LoadFromDir(dir)
+		if nil != err {
+			return nil, err
+		}
+		for _, migration := range migrations {
+			prefix := namePrefix(migration.Name)
+			if owner, ok := owners[prefix]; ok && owner != dir {
+				return nil, errors.New(fmt.Sprintf("migration prefix \"%s\" is used in both \"%s\" and \"%s\"", prefix, owner, dir))
+			}
+			owners[prefix] = dir
+			entries = append(entries, entry{migration: migration, prefix: prefix})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].prefix != entries[j].prefix {
+			return lessPrefix(entries[i].prefix, entries[j].prefix)
+		}
+		return entries[i].migration.Name < entries[j].migration.Name
+	})
+	migrations := make([]Migration, 0, len(entries))
+	for _, e := range entries {
+		migrations = append(migrations, e.migration)
+	}
+	return migrations, nil
+}
+
+// namePrefix returns the part of name before the first "_".
+func namePrefix(name string) string {
+	if i := strings.Index(name, "_"); 0 <= i {
+		return name[:i]
+	}
+	return name
+}
+
+// lessPrefix orders numeric prefixes by their value, so prefixes of different width are comparable, and others as strings.
+func lessPrefix(a, b string) bool {
+	numberA, errA := strconv.ParseInt(a, 10, 64)
+	numberB, errB := strconv.ParseInt(b, 10, 64)
+	if nil == errA && nil == errB && numberA != numberB {
+		return numberA < numberB
+	}
+	return a < b
+}
+
+// irreversible is the Down of migrations without down file.
+func irreversible(*dbr.Tx) error {
+	return ErrIrreversible
+}
+
+// fileMigrate returns a Migrate executing the statements of path, either from memory or by streaming the file.
+func (l FileLoader) fileMigrate(path string) (Migrate, error) {
+	info, err := os.Stat(path)
+	if nil != err {
+		return nil, err
+	}
+	if info.Size() > l.StreamThreshold {
+		return func(transaction *dbr.Tx) error {
+			file, err := os.Open(path)
+			if nil != err {
+				return err
+			}
+			defer file.Close()
+			return execStatements(transaction, file, l.Params)
+		}, nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if nil != err {
+		return nil, err
+	}
+	return func(transaction *dbr.Tx) error {
+		return execStatements(transaction, bytes.NewReader(content), l.Params)
+	}, nil
+}
+
+// ExecStatements executes every statement read from r one after the other, so only a single statement is held in memory.
+// PrefixPlaceholder is replaced by the TablePrefix of the MigrationManager that began transaction.
+func ExecStatements(transaction *dbr.Tx, r io.Reader) error {
+	return execStatements(transaction, r, nil)
+}
+
+// execStatements is ExecStatements binding params, see FileLoader.Params.
+func execStatements(transaction *dbr.Tx, r io.Reader, params map[string]interface{}) error {
+	scanner := NewStatementScanner(r)
+	scanner.Dialect = transactionDialect(transaction)
+	for scanner.Scan() {
+		statement, err := substitutePrefix(transaction, scanner.Statement())
+		if nil != err {
+			return err
+		}
+		statement, args, interpolated := bindParams(transaction, statement, params)
+		start := time.Now()
+		result, err := transaction.Exec(statement, args...)
+		if nil == err {
+			// drivers may not report affected rows for DDL, which then simply is not counted
+			RecordResult(transaction, result)
+		}
+		if nil != transaction.Session && nil != transaction.EventReceiver {
+			kvs := map[string]string{"sql": interpolated}
+			if nil != err {
+				return transaction.EventErrKv("gomigration.exec.error", err, kvs)
+			}
+			transaction.TimingKv("gomigration.exec", time.Since(start).Nanoseconds(), kvs)
+		}
+		if nil != err {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// StatementScanner splits sql read from an io.Reader into single statements separated by semicolons.
+// Semicolons inside quotes, backticks, dollar quotes like the $$ or $body$ around Postgres function bodies and comments
+// are ignored, comments are dropped from the statements.
+type StatementScanner struct {
+	// Dialect selects the comments recognized, "#" only starts one with MySQL, the default. Elsewhere it is an operator.
+	Dialect   Dialect
+	reader    *bufio.Reader
+	line      int
+	statement string
+	err       error
+}
+
+// NewStatementScanner returns a StatementScanner reading from r.
+func NewStatementScanner(r io.Reader) *StatementScanner {
+	return &StatementScanner{reader: bufio.NewReader(r), line: 1}
+}
+
+// Scan advances to the next statement and returns false when there is none left or an error occurred.
+func (s *StatementScanner) Scan() bool {
+	if nil != s.err {
+		return false
+	}
+	var buffer bytes.Buffer
+	for {
+		r, err := s.next()
+		if io.EOF == err {
+			break
+		}
+		if nil != err {
+			s.err = err
+			return false
+		}
+		switch {
+		case ';' == r:
+			if s.emit(&buffer) {
+				return true
+			}
+			continue
+		case '\'' == r || '"' == r || '`' == r:
+			buffer.WriteRune(r)
+			err = s.quoted(&buffer, r)
+		case '$' == r:
+			if tag := s.dollarTag(&buffer); "" != tag {
+				err = s.dollarQuoted(&buffer, tag)
+			} else {
+				buffer.WriteRune(r)
+			}
+		case '#' == r && MySQL == s.Dialect, '-' == r && s.peekComment():
+			buffer.WriteRune('\n')
+			err = s.lineComment()
+		case '/' == r && s.peek('*'):
+			err = s.blockComment(&buffer)
+		default:
+			buffer.WriteRune(r)
+		}
+		if nil != err {
+			s.err = err
+			return false
+		}
+	}
+	s.err = io.EOF
+	return s.emit(&buffer)
+}
+
+// Statement returns the statement found by the last call to Scan.
+func (s *StatementScanner) Statement() string {
+	return s.statement
+}
+
+// Err returns the first error that occurred while scanning, if any.
+func (s *StatementScanner) Err() error {
+	if io.EOF == s.err {
+		return nil
+	}
+	return s.err
+}
+
+func (s *StatementScanner) emit(buffer *bytes.Buffer) bool {
+	s.statement = strings.TrimSpace(buffer.String())
+	buffer.Reset()
+	return "" != s.statement
+}
+
+func (s *StatementScanner) next() (rune, error) {
+	r, _, err := s.reader.ReadRune()
+	if '\n' == r {
+		s.line++
+	}
+	return r, err
+}
+
+func (s *StatementScanner) peek(expected rune) bool {
+	r, _, err := s.reader.ReadRune()
+	if nil != err {
+		return false
+	}
+	if r == expected {
+		return true
+	}
+	s.reader.UnreadRune()
+	return false
+}
+
+// peekComment reports if the "-" just read starts a "-- " comment.
+func (s *StatementScanner) peekComment() bool {
+	next, err := s.reader.Peek(2)
+	if len(next) < 1 || '-' != next[0] {
+		return false
+	}
+	if len(next) < 2 {
+		return io.EOF == err
+	}
+	return ' ' == next[1] || '\t' == next[1] || '\n' == next[1] || '\r' == next[1]
+}
+
+func (s *StatementScanner) quoted(buffer *bytes.Buffer, quote rune) error {
+	start := s.line
+	for {
+		r, err := s.next()
+		if io.EOF == err {
+			return errors.New(fmt.Sprintf("unterminated %c quote starting on line %d", quote, start))
+		}
+		if nil != err {
+			return err
+		}
+		buffer.WriteRune(r)
+		if '\\' == r && '`' != quote {
+			escaped, err := s.next()
+			if nil != err {
+				return errors.New(fmt.Sprintf("unterminated %c quote starting on line %d", quote, start))
+			}
+			buffer.WriteRune(escaped)
+			continue
+		}
+		if r == quote {
+			return nil
+		}
+	}
+}
+
+// dollarTag returns the tag like "$$" or "$body$" if the "$" just read starts a dollar quote and "" otherwise, e.g. for
+// the placeholder "$1" or a "$" within an identifier.
+func (s *StatementScanner) dollarTag(buffer *bytes.Buffer) string {
+	if written := buffer.Bytes(); 0 < len(written) {
+		if last := written[len(written)-1]; '$' == last || isParamChar(last) {
+			return ""
+		}
+	}
+	for n := 1; ; n++ {
+		next, _ := s.reader.Peek(n)
+		if len(next) < n {
+			return ""
+		}
+		c := next[n-1]
+		if '$' == c {
+			tag := "$" + string(next)
+			s.reader.Discard(n)
+			return tag
+		}
+		if !isParamChar(c) || (1 == n && '0' <= c && c <= '9') {
+			return ""
+		}
+	}
+}
+
+// dollarQuoted copies a dollar quote opened by tag to buffer up to and including the closing tag.
+func (s *StatementScanner) dollarQuoted(buffer *bytes.Buffer, tag string) error {
+	start := s.line
+	buffer.WriteString(tag)
+	body := buffer.Len()
+	for {
+		r, err := s.next()
+		if io.EOF == err {
+			return errors.New(fmt.Sprintf("unterminated %s quote starting on line %d", tag, start))
+		}
+		if nil != err {
+			return err
+		}
+		buffer.WriteRune(r)
+		if '$' == r && bytes.HasSuffix(buffer.Bytes()[body:], []byte(tag)) {
+			return nil
+		}
+	}
+}
+
+func (s *StatementScanner) lineComment() error {
+	for {
+		r, err := s.next()
+		if io.EOF == err || '\n' == r {
+			return nil
+		}
+		if nil != err {
+			return err
+		}
+	}
+}
+
+// blockComment skips a /* */ comment but keeps MySQL's executable /*! */ comments.
+func (s *StatementScanner) blockComment(buffer *bytes.Buffer) error {
+	start := s.line
+	keep := s.peek('!')
+	if keep {
+		buffer.WriteString("/*!")
+	} else {
+		buffer.WriteRune(' ')
+	}
+	for {
+		r, err := s.next()
+		if io.EOF == err {
+			return errors.New(fmt.Sprintf("unterminated comment starting on line %d", start))
+		}
+		if nil != err {
+			return err
+		}
+		if keep {
+			buffer.WriteRune(r)
+		}
+		if '*' == r && s.peek('/') {
+			if keep {
+				buffer.WriteRune('/')
+			}
+			return nil
+		}
+	}
+}
+
+// SQLErrors are all problems found by ValidateSQL.
+type SQLErrors []error
+
+func (e SQLErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// ValidateSQL checks that the sql files of all migrations loaded from files can be split into statements, without
+// touching a database. It returns SQLErrors naming every broken file or nil. Other migrations are ignored.
+func ValidateSQL(migrations []Migration) error {
+	var problems SQLErrors
+	for _, migration := range migrations {
+		for _, path := range []string{migration.upFile, migration.downFile} {
+			if "" == path {
+				continue
+			}
+			if err := validateSQLFile(path); nil != err {
+				problems = append(problems, errors.New(fmt.Sprintf("%s: %s", path, err)))
+			}
+		}
+	}
+	if 0 == len(problems) {
+		return nil
+	}
+	return problems
+}
+
+func validateSQLFile(path string) error {
+	file, err := os.Open(path)
+	if nil != err {
+		return err
+	}
+	defer file.Close()
+	scanner := NewStatementScanner(file)
+	for scanner.Scan() {
+	}
+	return scanner.Err()
+}
+
+// readStatements returns all statements of the sql file at path.
+func readStatements(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if nil != err {
+		return nil, err
+	}
+	defer file.Close()
+	var statements []string
+	scanner := NewStatementScanner(file)
+	for scanner.Scan() {
+		statements = append(statements, scanner.Statement())
+	}
+	return statements, scanner.Err()
+}
@@ -0,0 +1,54 @@
+package gomigration
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files, content by name, to a new directory and returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); nil != err {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// scan returns the statements of content split by a StatementScanner of dialect.
+func scan(t *testing.T, dialect Dialect, content string) []string {
+	t.Helper()
+	scanner := NewStatementScanner(strings.NewReader(content))
+	scanner.Dialect = dialect
+	var statements []string
+	for scanner.Scan() {
+		statements = append(statements, scanner.Statement())
+	}
+	if err := scanner.Err(); nil != err {
+		t.Fatal(err)
+	}
+	return statements
+}
+
+func TestStatementScannerKeepsDollarQuotes(t *testing.T) {
+	function := "CREATE FUNCTION touch() RETURNS trigger AS $body$ BEGIN NEW.updated = now(); RETURN NEW; END; $body$ LANGUAGE plpgsql"
+	statements := scan(t, Postgres, function+";\nSELECT $$a;b$$, $1, price$eur FROM t;")
+	expected := []string{function, "SELECT $$a;b$$, $1, price$eur FROM t"}
+	if !reflect.DeepEqual(expected, statements) {
+		t.Errorf("expected %q, got %q", expected, statements)
+	}
+}
+
+func TestStatementScannerHashComments(t *testing.T) {
+	if statements := scan(t, MySQL, "SELECT 1; # comment; with semicolon\nSELECT 2;"); !reflect.DeepEqual([]string{"SELECT 1", "SELECT 2"}, statements) {
+		t.Errorf("expected # to start a comment on MySQL, got %q", statements)
+	}
+	if statements := scan(t, Postgres, "SELECT 5 # 3;\nSELECT 1;"); !reflect.DeepEqual([]string{"SELECT 5 # 3", "SELECT 1"}, statements) {
+		t.Errorf("expected # to be an operator on Postgres, got %q", statements)
+	}
+}
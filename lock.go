@@ -0,0 +1,123 @@
+package gomigration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+)
+
+// ErrMigrationInProgress is returned when the migration lock could not be acquired within LockTimeout, because another
+// process holds it while running migrations.
+var ErrMigrationInProgress = errors.New("another migration is in progress")
+
+// DefaultLockTimeout is the LockTimeout of MigrationManagers returned by the constructors.
+const DefaultLockTimeout = 30 * time.Second
+
+// lockRetryInterval is the pause between two attempts to get a Postgres advisory lock.
+const lockRetryInterval = 250 * time.Millisecond
+
+// Lock is an advisory lock held on a dedicated connection, pinned from the connection pool until it is released.
+type Lock struct {
+	conn    *sql.Conn
+	key     string
+	dialect Dialect
+}
+
+// LockKey returns the key of the advisory lock, which is "gomigration:" followed by the table name.
+func (mM MigrationManager) LockKey() string {
+	return "gomigration:" + mM.table()
+}
+
+// AcquireLock takes the advisory lock that keeps several processes from migrating the same database at once, waiting
+// for up to LockTimeout. MySQL uses GET_LOCK and Postgres pg_try_advisory_lock, while SQLite, which only allows a
+// single writer anyway, gets a lock that does nothing.
+func (mM MigrationManager) AcquireLock() (*Lock, error) {
+	return mM.acquireLock(context.Background())
+}
+
+func (mM MigrationManager) acquireLock(ctx context.Context) (*Lock, error) {
+	lock := &Lock{key: mM.LockKey(), dialect: mM.Dialect}
+	if SQLite == mM.Dialect {
+		return lock, nil
+	}
+	db := mM.Connection.Db
+	if nil != mM.LockConnection {
+		db = mM.LockConnection
+	}
+	conn, err := db.Conn(ctx)
+	if nil != err {
+		return nil, err
+	}
+	lock.conn = conn
+	acquired := false
+	if MySQL == mM.Dialect {
+		var result sql.NullInt64
+		seconds := int64((mM.LockTimeout + time.Second - 1) / time.Second)
+		err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lock.key, seconds).Scan(&result)
+		acquired = 1 == result.Int64
+	} else {
+		acquired, err = lock.tryPostgres(ctx, mM.LockTimeout)
+	}
+	if nil == err && !acquired {
+		err = fmt.Errorf("%w: could not get lock \"%s\" within %s", ErrMigrationInProgress, lock.key, mM.LockTimeout)
+	}
+	if nil != err {
+		conn.Close()
+		return nil, err
+	}
+	return lock, nil
+}
+
+// tryPostgres polls pg_try_advisory_lock until it succeeds or timeout elapsed.
+func (l *Lock) tryPostgres(ctx context.Context, timeout time.Duration) (bool, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		var acquired bool
+		if err := l.conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock("+l.postgresKey()+")").Scan(&acquired); nil != err {
+			return false, err
+		}
+		if acquired || time.Now().After(deadline) {
+			return acquired, nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// postgresKey maps the key to the integer Postgres identifies advisory locks by.
+func (l *Lock) postgresKey() string {
+	hash := fnv.New64a()
+	hash.Write([]byte(l.key))
+	return strconv.FormatInt(int64(hash.Sum64()), 10)
+}
+
+// Key returns the key of the lock.
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Release gives the lock up and closes its connection. It does not take a context, so cancelling the context a
+// migration run was started with can not keep the lock from being released.
+func (l *Lock) Release() error {
+	if nil == l.conn {
+		return nil
+	}
+	var err error
+	if MySQL == l.dialect {
+		_, err = l.conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", l.key)
+	} else {
+		_, err = l.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock("+l.postgresKey()+")")
+	}
+	if closeErr := l.conn.Close(); nil == err {
+		err = closeErr
+	}
+	l.conn = nil
+	return err
+}
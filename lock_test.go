@@ -0,0 +1,116 @@
+package gomigration
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gocraft/dbr"
+)
+
+// lockServer is a fake database answering the advisory lock queries of Postgres and recording every statement.
+type lockServer struct {
+	mutex      sync.Mutex
+	statements []string
+}
+
+func (s *lockServer) Connect(context.Context) (driver.Conn, error) { return lockConn{s}, nil }
+func (s *lockServer) Driver() driver.Driver                        { return nil }
+
+// unlocked checks if the lock was released.
+func (s *lockServer) unlocked() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, statement := range s.statements {
+		if strings.Contains(statement, "pg_advisory_unlock") {
+			return true
+		}
+	}
+	return false
+}
+
+type lockConn struct{ server *lockServer }
+
+func (c lockConn) Prepare(query string) (driver.Stmt, error) { return lockStmt{c.server, query}, nil }
+func (c lockConn) Close() error                              { return nil }
+func (c lockConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type lockStmt struct {
+	server *lockServer
+	query  string
+}
+
+func (s lockStmt) Close() error  { return nil }
+func (s lockStmt) NumInput() int { return -1 }
+func (s lockStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.record()
+	return driver.RowsAffected(0), nil
+}
+func (s lockStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.record()
+	return &lockRows{}, nil
+}
+func (s lockStmt) record() {
+	s.server.mutex.Lock()
+	s.server.statements = append(s.server.statements, s.query)
+	s.server.mutex.Unlock()
+}
+
+type lockRows struct{ done bool }
+
+func (r *lockRows) Columns() []string { return []string{"result"} }
+func (r *lockRows) Close() error      { return nil }
+func (r *lockRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = true
+	return nil
+}
+
+// lockingManager returns a MigrationManager holding a Postgres advisory lock on a fake database while it runs.
+func lockingManager(t *testing.T) (MigrationManager, *lockServer) {
+	t.Helper()
+	server := &lockServer{}
+	db := sql.OpenDB(server)
+	t.Cleanup(func() { db.Close() })
+	connection := testConnection(t)
+	execute(t, connection, createTableSQL(defaultTableName, SQLite, KeyAutoIncrement))
+	mM := MigrationManager{Connection: connection, Dialect: Postgres, Locking: true, LockConnection: db, LockTimeout: DefaultLockTimeout}
+	return mM, server
+}
+
+func TestLockIsReleasedAfterFailure(t *testing.T) {
+	mM, server := lockingManager(t)
+	failing := Migration{Name: "failing", Up: func(*dbr.Tx) error {
+		return errors.New("failed")
+	}}
+	if err := mM.MigrationRunnerContext(context.Background(), []Migration{failing}); nil == err {
+		t.Fatal("expected the migration to fail")
+	}
+	if !server.unlocked() {
+		t.Error("expected the lock to be released")
+	}
+}
+
+func TestLockIsReleasedAfterCancel(t *testing.T) {
+	mM, server := lockingManager(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	migrations := []Migration{{Name: "first", Up: func(*dbr.Tx) error {
+		cancel()
+		return nil
+	}}, {Name: "second", Up: noop}}
+	if err := mM.MigrationRunnerContext(ctx, migrations); !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("expected ErrInterrupted, got %v", err)
+	}
+	if !server.unlocked() {
+		t.Error("expected the lock to be released")
+	}
+}
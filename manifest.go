@@ -0,0 +1,80 @@
+package gomigration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// manifest is the content of a manifest file read by LoadFromManifest.
+type manifest struct {
+	Migrations []manifestEntry `json:"migrations"`
+}
+
+type manifestEntry struct {
+	Name string `json:"name"`
+	Up   string `json:"up"`
+	Down string `json:"down"`
+}
+
+// LoadFromManifest loads the migrations listed in the manifest at path using the default FileLoader.
+func LoadFromManifest(path string) ([]Migration, error) {
+	return NewFileLoader().LoadFromManifest(path)
+}
+
+// LoadFromManifest loads the migrations listed in the JSON manifest at path in the order they are listed, which makes
+// the order explicit and independent of the file names:
+//
+//	{"migrations": [
+//		{"name": "create_users", "up": "sql/users.up.sql", "down": "sql/users.down.sql"}
+//	]}
+//
+// Relative paths are resolved against the directory of the manifest. The down file may be left out with
+// AllowMissingDown, which makes the migration irreversible. Names must be unique and all files listed have to exist,
+// a listed down file that is missing is an error even with AllowMissingDown.
+func (l FileLoader) LoadFromManifest(path string) ([]Migration, error) {
+	content, err := ioutil.ReadFile(path)
+	if nil != err {
+		return nil, err
+	}
+	var m manifest
+	if err = json.Unmarshal(content, &m); nil != err {
+		return nil, fmt.Errorf("invalid manifest \"%s\": %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	resolve := func(file string) string {
+		if "" == file || filepath.IsAbs(file) {
+			return file
+		}
+		return filepath.Join(dir, file)
+	}
+	names := make(map[string]bool, len(m.Migrations))
+	migrations := make([]Migration, 0, len(m.Migrations))
+	for i, entry := range m.Migrations {
+		if "" == entry.Name || "" == entry.Up {
+			return nil, errors.New(fmt.Sprintf("migration %d of manifest \"%s\" needs a name and an up file", i+1, path))
+		}
+		if names[entry.Name] {
+			return nil, errors.New(fmt.Sprintf("migration \"%s\" is listed twice in manifest \"%s\"", entry.Name, path))
+		}
+		names[entry.Name] = true
+		if "" == entry.Down && !l.AllowMissingDown {
+			return nil, errors.New(fmt.Sprintf("migration \"%s\" of manifest \"%s\" has no down file", entry.Name, path))
+		}
+		down := resolve(entry.Down)
+		if "" != down {
+			if _, err := os.Stat(down); nil != err {
+				return nil, fmt.Errorf("down file of migration \"%s\" of manifest \"%s\": %w", entry.Name, path, err)
+			}
+		}
+		migration, err := l.load(entry.Name, resolve(entry.Up), down)
+		if nil != err {
+			return nil, err
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
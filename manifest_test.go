@@ -0,0 +1,40 @@
+package gomigration
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFromManifest(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"b.up.sql":   "SELECT 1;",
+		"b.down.sql": "SELECT 1;",
+		"a.up.sql":   "SELECT 2;",
+		"m.json":     `{"migrations":[{"name":"z","up":"b.up.sql","down":"b.down.sql"},{"name":"y","up":"a.up.sql"}]}`,
+	})
+	path := filepath.Join(dir, "m.json")
+	if _, err := LoadFromManifest(path); nil == err {
+		t.Fatal("expected the missing down file to be rejected")
+	}
+	loader := NewFileLoader()
+	loader.AllowMissingDown = true
+	migrations, err := loader.LoadFromManifest(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 2 != len(migrations) || "z" != migrations[0].Name || migrations[0].Irreversible || !migrations[1].Irreversible {
+		t.Errorf("expected z and the irreversible y in the order of the manifest, got %+v", migrations)
+	}
+}
+
+func TestLoadFromManifestRejectsMissingListedDown(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.up.sql": "SELECT 1;",
+		"m.json":   `{"migrations":[{"name":"a","up":"a.up.sql","down":"a.down.sql"}]}`,
+	})
+	loader := NewFileLoader()
+	loader.AllowMissingDown = true
+	if _, err := loader.LoadFromManifest(filepath.Join(dir, "m.json")); nil == err {
+		t.Error("expected the listed but missing down file to be rejected")
+	}
+}
@@ -0,0 +1,58 @@
+package gomigration
+
+import (
+	"time"
+)
+
+// Metrics receives counters and durations of the migrations the runners apply, e.g. to export them to Prometheus.
+type Metrics interface {
+	// IncApplied counts a migration that was applied.
+	IncApplied()
+	// IncFailed counts a migration that failed and was rolled back.
+	IncFailed()
+	// IncSkipped counts a migration that was skipped because it ran before.
+	IncSkipped()
+	// ObserveDuration records how long applying the migration called name took.
+	ObserveDuration(name string, d time.Duration)
+}
+
+// Logger receives the warnings of a MigrationManager. *log.Logger implements it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) IncApplied()                                  {}
+func (nopMetrics) IncFailed()                                   {}
+func (nopMetrics) IncSkipped()                                  {}
+func (nopMetrics) ObserveDuration(name string, d time.Duration) {}
+
+// metrics returns the configured Metrics or one discarding everything.
+func (mM MigrationManager) metrics() Metrics {
+	if nil == mM.Metrics {
+		return nopMetrics{}
+	}
+	return mM.Metrics
+}
+
+// observe reports the outcome of applying migration, which started at start, to the Metrics.
+func (mM MigrationManager) observe(migration Migration, start time.Time, err error) {
+	if nil != err {
+		mM.metrics().IncFailed()
+		return
+	}
+	elapsed := time.Since(start)
+	mM.metrics().IncApplied()
+	mM.metrics().ObserveDuration(migration.Name, elapsed)
+	if 0 < mM.SlowThreshold && elapsed > mM.SlowThreshold {
+		mM.warn("migration \"%s\" is slow, it took %s to apply which exceeds %s", migration.Name, elapsed, mM.SlowThreshold)
+	}
+}
+
+// warn passes a warning on to the Logger, if there is one.
+func (mM MigrationManager) warn(format string, v ...interface{}) {
+	if nil != mM.Logger {
+		mM.Logger.Printf(format, v...)
+	}
+}
@@ -0,0 +1,54 @@
+package gomigration
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocraft/dbr"
+)
+
+func TestSlowMigrationsAreLogged(t *testing.T) {
+	mM := testManager(t)
+	var logged bytes.Buffer
+	mM.Logger = log.New(&logged, "", 0)
+	mM.SlowThreshold = 10 * time.Millisecond
+	slow := func(*dbr.Tx) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}
+	mM.MigrationRunner([]Migration{{Name: "fast", Up: noop}, {Name: "slow", Up: slow}})
+	if !strings.Contains(logged.String(), "migration \"slow\" is slow") {
+		t.Errorf("expected a warning about the slow migration, got %q", logged.String())
+	}
+	if strings.Contains(logged.String(), "\"fast\"") {
+		t.Errorf("expected no warning about the fast migration, got %q", logged.String())
+	}
+}
+
+func TestProgressReportsEveryAppliedMigration(t *testing.T) {
+	mM := testManager(t)
+	markExecuted(t, mM, Migration{Name: "first"})
+	var reported [][2]int
+	mM.Progress = func(done, total int) {
+		reported = append(reported, [2]int{done, total})
+	}
+	mM.MigrationRunner([]Migration{{Name: "first", Up: noop}, {Name: "second", Up: noop}, {Name: "third", Up: noop}})
+	if 2 != len(reported) || ([2]int{1, 2}) != reported[0] || ([2]int{2, 2}) != reported[1] {
+		t.Errorf("expected progress 1/2 and 2/2, got %v", reported)
+	}
+}
+
+func TestProgressIsWeighted(t *testing.T) {
+	mM := testManager(t)
+	var reported [][2]int
+	mM.Progress = func(done, total int) {
+		reported = append(reported, [2]int{done, total})
+	}
+	mM.MigrationRunner([]Migration{{Name: "backfill", Up: noop, Weight: 50}, {Name: "index", Up: noop}, {Name: "negative", Up: noop, Weight: -3}})
+	if 3 != len(reported) || ([2]int{50, 52}) != reported[0] || ([2]int{51, 52}) != reported[1] || ([2]int{52, 52}) != reported[2] {
+		t.Errorf("expected progress 50/52, 51/52 and 52/52, got %v", reported)
+	}
+}
@@ -0,0 +1,31 @@
+package gomigration
+
+import (
+	"github.com/gocraft/dbr"
+)
+
+// MigrateWithContext is like Migrate but also gets a MigrationContext to look at the state of the other migrations.
+type MigrateWithContext func(*dbr.Tx, MigrationContext) error
+
+// MigrationContext gives a running migration read access to the meta table within its transaction.
+type MigrationContext struct {
+	manager     MigrationManager
+	transaction *dbr.Tx
+}
+
+// IsExecuted checks if the migration with the given name was applied before.
+func (c MigrationContext) IsExecuted(name string) bool {
+	return c.manager.checkIfExecuted(c.transaction, Migration{Name: name})
+}
+
+// TableName returns the name of the meta table.
+func (c MigrationContext) TableName() string {
+	return c.manager.table()
+}
+
+// WithContext adapts fn to a Migrate, so it can be used as Up or Down of a Migration run by this MigrationManager.
+func (mM MigrationManager) WithContext(fn MigrateWithContext) Migrate {
+	return func(transaction *dbr.Tx) error {
+		return fn(transaction, MigrationContext{manager: mM, transaction: transaction})
+	}
+}
@@ -0,0 +1,147 @@
+package gomigration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/gocraft/dbr"
+)
+
+// ErrNestedTransaction is returned when a migration begins, commits or rolls back a transaction itself instead of only
+// using the transaction it was given. Such a migration is rolled back, although a transaction it began on its own may
+// already have been committed or still block the migration's transaction.
+var ErrNestedTransaction = errors.New("migration must only use the given transaction and not begin, commit or roll back transactions itself")
+
+// txMonitor receives the events of the session a migration's transaction was begun on. As the *dbr.Tx shares that
+// session, every transaction the migration begins, commits or rolls back itself is noticed while active is set.
+// The events are passed on to the receiver of the running migration or to base.
+type txMonitor struct {
+	dbr.EventReceiver
+	base    dbr.EventReceiver
+	active  bool
+	misused bool
+	// affected counts the rows affected by the running migration as reported by AddAffected.
+	affected int64
+	// result is the last result reported by RecordResult.
+	result sql.Result
+	// prefix and dialect are the TablePrefix and Dialect of the MigrationManager.
+	prefix  string
+	dialect Dialect
+}
+
+func (m *txMonitor) Event(eventName string) {
+	m.watch(eventName)
+	m.EventReceiver.Event(eventName)
+}
+
+func (m *txMonitor) EventErr(eventName string, err error) error {
+	m.watch(eventName)
+	return m.EventReceiver.EventErr(eventName, err)
+}
+
+func (m *txMonitor) watch(eventName string) {
+	switch eventName {
+	case "dbr.begin", "dbr.begin.error", "dbr.commit", "dbr.commit.error", "dbr.rollback":
+		if m.active {
+			m.misused = true
+		}
+	}
+}
+
+// AddAffected adds the rows affected according to result to the count checked against the ExpectedAffected of the
+// running migration. It does nothing for transactions not begun by the MigrationManager.
+func AddAffected(transaction *dbr.Tx, result sql.Result) error {
+	if nil == transaction.Session {
+		return nil
+	}
+	monitor, ok := transaction.EventReceiver.(*txMonitor)
+	if !ok {
+		return nil
+	}
+	affected, err := result.RowsAffected()
+	if nil != err {
+		return err
+	}
+	monitor.affected += affected
+	return nil
+}
+
+// RecordResult remembers result as the last result of the running migration, which is passed to OnResult, and adds its
+// affected rows like AddAffected. The statements of sql files are recorded automatically, migrations written in Go opt
+// in by calling it. It does nothing for transactions not begun by the MigrationManager.
+func RecordResult(transaction *dbr.Tx, result sql.Result) error {
+	if nil == transaction.Session {
+		return nil
+	}
+	if monitor, ok := transaction.EventReceiver.(*txMonitor); ok {
+		monitor.result = result
+	}
+	return AddAffected(transaction, result)
+}
+
+// checkAffected compares the rows counted for migration with its ExpectedAffected.
+func checkAffected(transaction *dbr.Tx, migration Migration) error {
+	var monitor *txMonitor
+	if nil != transaction.Session {
+		monitor, _ = transaction.EventReceiver.(*txMonitor)
+	}
+	if nil == monitor {
+		return migrationError(migration, "up", errors.New("ExpectedAffected needs a transaction begun by the MigrationManager"))
+	}
+	if monitor.affected != migration.ExpectedAffected {
+		return migrationError(migration, "up", errors.New(fmt.Sprintf("expected %d affected rows but %d were affected", migration.ExpectedAffected, monitor.affected)))
+	}
+	return nil
+}
+
+// begin starts a transaction with the given isolation level on a session of the manager's connection that reports to
+// a txMonitor, keeping the event receiver of session, and sets the search_path to Schema.
+func (mM MigrationManager) begin(session *dbr.Session, isolation sql.IsolationLevel) (*dbr.Tx, *txMonitor, error) {
+	receiver := session.EventReceiver
+	if nil == receiver {
+		receiver = &dbr.NullEventReceiver{}
+	}
+	monitor := &txMonitor{EventReceiver: receiver, base: receiver, prefix: mM.TablePrefix, dialect: mM.Dialect}
+	monitoredSession := mM.Connection.NewSession(monitor)
+	var transaction *dbr.Tx
+	if sql.LevelDefault == isolation {
+		var err error
+		if transaction, err = monitoredSession.Begin(); nil != err {
+			return nil, nil, err
+		}
+	} else {
+		tx, err := mM.Connection.Db.BeginTx(context.Background(), &sql.TxOptions{Isolation: isolation})
+		if nil != err {
+			return nil, nil, err
+		}
+		transaction = &dbr.Tx{Session: monitoredSession, Tx: tx}
+	}
+	if err := mM.setSearchPath(transaction); nil != err {
+		transaction.Rollback()
+		return nil, nil, err
+	}
+	return transaction, monitor, nil
+}
+
+// monitored runs apply while monitor watches for transactions handled by the migration itself.
+func (mM MigrationManager) monitored(transaction *dbr.Tx, monitor *txMonitor, migration Migration, direction string,
+	apply func(*dbr.Tx, Migration) error) error {
+	if nil != mM.QueryReceiver {
+		monitor.EventReceiver = mM.QueryReceiver.ForMigration(migration.Name)
+	}
+	monitor.active = true
+	monitor.affected = 0
+	monitor.result = nil
+	err := apply(transaction, migration)
+	monitor.active = false
+	monitor.EventReceiver = monitor.base
+	if nil == err && monitor.misused {
+		err = migrationError(migration, direction, ErrNestedTransaction)
+	}
+	if nil == err && nil != mM.OnResult && nil != monitor.result {
+		mM.OnResult(migration.Name, direction, monitor.result)
+	}
+	return err
+}
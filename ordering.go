@@ -0,0 +1,42 @@
+package gomigration
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gocraft/dbr"
+)
+
+// Ordering selects the order of the rows of the meta table that operations depending on it use, like RollbackN,
+// History and the VersionHead.
+type Ordering int
+
+const (
+	// OrderByID orders by id, the order the migrations were applied in. It is the default. With KeyUUID, whose ids
+	// are random, it orders by execution instead.
+	OrderByID Ordering = iota
+	// OrderByExecution orders by execution time and ties, like migrations applied within the same second, by id.
+	OrderByExecution
+	// OrderByName orders by name and ties by id, for names with a sortable prefix like "001_".
+	OrderByName
+)
+
+// checkOrdering returns an error if OrderBy is not one of the Ordering constants.
+func (mM MigrationManager) checkOrdering() error {
+	switch mM.OrderBy {
+	case OrderByID, OrderByExecution, OrderByName:
+		return nil
+	}
+	return errors.New(fmt.Sprintf("unknown OrderBy %d", mM.OrderBy))
+}
+
+// ordered applies OrderBy to builder.
+func (mM MigrationManager) ordered(builder *dbr.SelectBuilder) *dbr.SelectBuilder {
+	switch {
+	case OrderByName == mM.OrderBy:
+		builder = builder.OrderBy("name")
+	case OrderByExecution == mM.OrderBy, KeyUUID == mM.Keys:
+		builder = builder.OrderBy("execution")
+	}
+	return builder.OrderBy("id")
+}
@@ -0,0 +1,17 @@
+package gomigration
+
+import (
+	"testing"
+)
+
+func TestWithOrderBy(t *testing.T) {
+	if err := initError(testConnection(t), WithDialect(SQLite), WithOrderBy(Ordering(42))); nil == err {
+		t.Fatal("expected an unknown OrderBy to be rejected")
+	}
+	mM := NewMigrationManager(testConnection(t), WithDialect(SQLite), WithOrderBy(OrderByName))
+	markExecuted(t, mM, Migration{Name: "002_second"}, Migration{Name: "001_first"})
+	executed, err := mM.ListExecuted(mM.Connection.NewSession(nil))
+	if nil != err || 2 != len(executed) || "001_first" != executed[0].Name {
+		t.Errorf("expected the rows ordered by name, got %v, %v", executed, err)
+	}
+}
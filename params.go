@@ -0,0 +1,111 @@
+package gomigration
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocraft/dbr"
+)
+
+// bindParams replaces the placeholders ":name" of statement whose names are keys of params by the bind placeholder
+// of the dialect of the MigrationManager that began transaction and returns the values to bind in the same order.
+// Placeholders inside quotes and backticks are left alone, as well as casts like "::int", assignments like ":=" and
+// names that are no keys of params. The last result is statement with the values written as literals instead, for
+// reporting it to the receivers of the migration.
+func bindParams(transaction *dbr.Tx, statement string, params map[string]interface{}) (string, []interface{}, string) {
+	if 0 == len(params) || !strings.Contains(statement, ":") {
+		return statement, nil, statement
+	}
+	dialect := transactionDialect(transaction)
+	var builder, interpolated strings.Builder
+	copied := 0
+	var args []interface{}
+	var quote byte
+	for i := 0; i < len(statement); i++ {
+		c := statement[i]
+		switch {
+		case 0 != quote:
+			if '\\' == c && '`' != quote && i+1 < len(statement) {
+				builder.WriteByte(c)
+				i++
+				c = statement[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case '\'' == c || '"' == c || '`' == c:
+			quote = c
+		case ':' == c && i+1 < len(statement) && (':' == statement[i+1] || '=' == statement[i+1]):
+			builder.WriteString(statement[i : i+2])
+			i++
+			continue
+		case ':' == c:
+			end := i + 1
+			for end < len(statement) && isParamChar(statement[end]) {
+				end++
+			}
+			if value, ok := params[statement[i+1:end]]; ok && end > i+1 {
+				args = append(args, value)
+				interpolated.WriteString(statement[copied:i])
+				interpolated.WriteString(sqlLiteral(dialect, value))
+				copied = end
+				if Postgres == dialect {
+					builder.WriteString("$" + strconv.Itoa(len(args)))
+				} else {
+					builder.WriteByte('?')
+				}
+				i = end - 1
+				continue
+			}
+		}
+		builder.WriteByte(c)
+	}
+	interpolated.WriteString(statement[copied:])
+	return builder.String(), args, interpolated.String()
+}
+
+// sqlLiteral returns value written as a literal of dialect.
+func sqlLiteral(dialect Dialect, value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case driver.Valuer:
+		converted, err := v.Value()
+		if nil != err {
+			return "NULL"
+		}
+		return sqlLiteral(dialect, converted)
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	case time.Time:
+		value = v.Format(timeFormat)
+	case []byte:
+		value = string(v)
+	}
+	literal := strings.Replace(fmt.Sprint(value), "'", "''", -1)
+	if MySQL == dialect {
+		literal = strings.Replace(literal, `\`, `\\`, -1)
+	}
+	return "'" + literal + "'"
+}
+
+// transactionDialect returns the Dialect of the MigrationManager that began transaction, MySQL if it was not.
+func transactionDialect(transaction *dbr.Tx) Dialect {
+	if nil != transaction.Session {
+		if monitor, ok := transaction.EventReceiver.(*txMonitor); ok {
+			return monitor.dialect
+		}
+	}
+	return MySQL
+}
+
+func isParamChar(c byte) bool {
+	return '_' == c || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
@@ -0,0 +1,62 @@
+package gomigration
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gocraft/dbr"
+)
+
+func TestBindParams(t *testing.T) {
+	params := map[string]interface{}{"name": "x'y", "n": 3}
+	statement, args, interpolated := bindParams(&dbr.Tx{}, "INSERT INTO t (a, b, c) VALUES (:name, ':name', :n::int) -- :other, @v := :missing", params)
+	expected := "INSERT INTO t (a, b, c) VALUES (?, ':name', ?::int) -- :other, @v := :missing"
+	if expected != statement || !reflect.DeepEqual([]interface{}{"x'y", 3}, args) {
+		t.Errorf("expected %q with x'y and 3, got %q with %v", expected, statement, args)
+	}
+	expected = "INSERT INTO t (a, b, c) VALUES ('x''y', ':name', 3::int) -- :other, @v := :missing"
+	if expected != interpolated {
+		t.Errorf("expected %q, got %q", expected, interpolated)
+	}
+}
+
+func TestFileLoaderBindsParams(t *testing.T) {
+	mM := testManager(t)
+	dir := writeFiles(t, map[string]string{
+		"settings.up.sql":   "CREATE TABLE settings (name TEXT, value TEXT);\nINSERT INTO settings VALUES ('region', :region);",
+		"settings.down.sql": "DROP TABLE settings;",
+	})
+	loader := NewFileLoader()
+	loader.Params = map[string]interface{}{"region": "eu-west-1"}
+	migrations, err := loader.LoadFromDir(dir)
+	if nil != err {
+		t.Fatal(err)
+	}
+	mM.MigrationRunner(migrations)
+	region, err := mM.Connection.NewSession(nil).Select("value").From("settings").Where("name = ?", "region").ReturnString()
+	if nil != err || "eu-west-1" != region {
+		t.Errorf("expected the bound region, got %q, %v", region, err)
+	}
+}
+
+func TestAuditLogShowsBoundParams(t *testing.T) {
+	mM := testManager(t)
+	var audit bytes.Buffer
+	mM.QueryReceiver = NewSQLAuditLog(&audit)
+	dir := writeFiles(t, map[string]string{
+		"settings.up.sql":   "CREATE TABLE settings (name TEXT, value TEXT);\nINSERT INTO settings VALUES ('owner', :owner);",
+		"settings.down.sql": "DROP TABLE settings;",
+	})
+	loader := NewFileLoader()
+	loader.Params = map[string]interface{}{"owner": "O'Brien"}
+	migrations, err := loader.LoadFromDir(dir)
+	if nil != err {
+		t.Fatal(err)
+	}
+	mM.MigrationRunner(migrations)
+	if !strings.Contains(audit.String(), "INSERT INTO settings VALUES ('owner', 'O''Brien');") {
+		t.Errorf("expected the bound value in the audit log, got %s", audit.String())
+	}
+}
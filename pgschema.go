@@ -0,0 +1,38 @@
+package gomigration
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/gocraft/dbr"
+)
+
+// schemaName matches the names accepted for Schema, which are used unquoted like table names.
+var schemaName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// checkSchemaName returns an error if Schema is set but not a valid schema name or the Dialect is not Postgres.
+func (mM MigrationManager) checkSchemaName() error {
+	if "" == mM.Schema {
+		return nil
+	}
+	if Postgres != mM.Dialect {
+		return errors.New("Schema is only supported with the Postgres dialect")
+	}
+	if !schemaName.MatchString(mM.Schema) || 63 < len(mM.Schema) {
+		return errors.New(fmt.Sprintf("invalid schema \"%s\", it must consist of lower case letters, digits and underscores", mM.Schema))
+	}
+	return nil
+}
+
+// setSearchPath points the search_path of transaction to Schema until it ends, if Schema is set.
+func (mM MigrationManager) setSearchPath(transaction *dbr.Tx) error {
+	if "" == mM.Schema {
+		return nil
+	}
+	if err := mM.checkSchemaName(); nil != err {
+		return err
+	}
+	_, err := transaction.Exec("SET LOCAL search_path TO " + mM.Schema)
+	return err
+}
@@ -0,0 +1,29 @@
+package gomigration
+
+import (
+	"testing"
+)
+
+func TestCheckSchemaName(t *testing.T) {
+	tests := map[string]bool{"": true, "tenant_42": true, "_shared": true, "Tenant": false, "42tenant": false, "a-b": false, "a; DROP": false}
+	for schema, valid := range tests {
+		mM := MigrationManager{Dialect: Postgres, Schema: schema}
+		if err := mM.checkSchemaName(); valid != (nil == err) {
+			t.Errorf("schema %q: expected valid %v, got %v", schema, valid, err)
+		}
+	}
+	if "tenant_42.dbMigrations" != (MigrationManager{Dialect: Postgres, Schema: "tenant_42"}).TableName() {
+		t.Error("expected the meta table to be qualified with the schema")
+	}
+}
+
+func TestWithSchemaIsRejectedBeforeCreatingTables(t *testing.T) {
+	connection := testConnection(t)
+	if err := initError(connection, WithDialect(SQLite), WithSchema("tenant")); nil == err {
+		t.Fatal("expected Schema to be rejected on SQLite")
+	}
+	var tables []string
+	if _, err := connection.NewSession(nil).SelectBySql("SELECT name FROM sqlite_master WHERE type = 'table'").LoadValues(&tables); nil != err || 0 != len(tables) {
+		t.Errorf("expected no table to be created, got %v, %v", tables, err)
+	}
+}
@@ -0,0 +1,92 @@
+package gomigration
+
+import (
+	"database/sql"
+
+	"github.com/gocraft/dbr"
+)
+
+// PlanAction is what running the migrations would do with a single migration.
+type PlanAction string
+
+const (
+	// PlanApply runs Up.
+	PlanApply PlanAction = "apply"
+	// PlanSkipExecuted skips a migration that ran before.
+	PlanSkipExecuted PlanAction = "skip-executed"
+	// PlanSkipCondition skips a migration whose ShouldRun returned false.
+	PlanSkipCondition PlanAction = "skip-condition"
+	// PlanMarkBaseline marks a Baseline migration as executed without running Up.
+	PlanMarkBaseline PlanAction = "mark-baseline"
+	// PlanMarkReplaced marks a migration as executed without running Up because a migration it replaces ran before.
+	PlanMarkReplaced PlanAction = "mark-replaced"
+	// PlanAwaitApproval leaves a migration with RequiresApproval pending.
+	PlanAwaitApproval PlanAction = "await-approval"
+)
+
+// PlanItem is the planned action for a single migration.
+type PlanItem struct {
+	Name   string
+	Action PlanAction
+	// Reason explains the action in words.
+	Reason string
+	// OutOfOrder is set for a pending migration listed before a migration that was already executed.
+	OutOfOrder bool
+}
+
+// Plan returns what MigrationRunner would do with each of the migrations, in the order it would do it. With
+// StopAtApproval it ends at the first migration awaiting approval, like the runners.
+// The ShouldRun conditions of pending migrations are evaluated within a transaction that is rolled back afterwards,
+// so they must not change anything.
+func (mM MigrationManager) Plan(session *dbr.Session, migrations []Migration) ([]PlanItem, error) {
+	if err := mM.CheckIfSane(migrations); nil != err {
+		return nil, err
+	}
+	executed, err := mM.executedSet(session)
+	if nil != err {
+		return nil, err
+	}
+	lastExecuted := -1
+	for i, migration := range migrations {
+		if executed[mM.normalize(migration.Name)] {
+			lastExecuted = i
+		}
+	}
+	transaction, _, err := mM.begin(session, sql.LevelDefault)
+	if nil != err {
+		return nil, err
+	}
+	defer transaction.Rollback()
+	plan := make([]PlanItem, 0, len(migrations))
+	for i, migration := range migrations {
+		item := PlanItem{Name: migration.Name, Action: PlanApply, Reason: "not executed yet"}
+		replaced := mM.executedReplaced(session, migration)
+		switch {
+		case executed[mM.normalize(migration.Name)]:
+			item.Action, item.Reason = PlanSkipExecuted, "executed before"
+		case migration.RequiresApproval:
+			item.Action, item.Reason = PlanAwaitApproval, "requires approval, apply it with RunByName"
+		case migration.Baseline:
+			item.Action, item.Reason = PlanMarkBaseline, "baseline, marked as executed without running"
+		case "" != replaced:
+			item.Action, item.Reason = PlanMarkReplaced, "replaces executed \""+replaced+"\", marked as executed without running"
+		case nil != migration.ShouldRun:
+			run, err := migration.ShouldRun(transaction)
+			if nil != err {
+				return nil, migrationError(migration, "up", err)
+			}
+			if !run {
+				item.Action, item.Reason = PlanSkipCondition, "ShouldRun returned false, marked as executed"
+				if migration.SkipWithoutMarking {
+					item.Reason = "ShouldRun returned false, left pending"
+				}
+			}
+		}
+		item.OutOfOrder = PlanSkipExecuted != item.Action && i < lastExecuted
+		plan = append(plan, item)
+		if PlanAwaitApproval == item.Action && mM.StopAtApproval {
+			break
+		}
+	}
+	return plan, nil
+}
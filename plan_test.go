@@ -0,0 +1,21 @@
+package gomigration
+
+import (
+	"testing"
+)
+
+func TestPlanStopsAtApproval(t *testing.T) {
+	mM := testManager(t)
+	markExecuted(t, mM, Migration{Name: "first"})
+	migrations := []Migration{{Name: "first", Up: noop}, {Name: "gated", Up: noop, RequiresApproval: true}, {Name: "last", Up: noop}}
+	session := mM.Connection.NewSession(nil)
+	plan, err := mM.Plan(session, migrations)
+	if nil != err || 3 != len(plan) || PlanAwaitApproval != plan[1].Action || PlanApply != plan[2].Action {
+		t.Fatalf("expected the gated migration to be skipped, got %+v, %v", plan, err)
+	}
+	mM.StopAtApproval = true
+	plan, err = mM.Plan(session, migrations)
+	if nil != err || 2 != len(plan) || PlanAwaitApproval != plan[1].Action {
+		t.Errorf("expected the plan to end at the gated migration, got %+v, %v", plan, err)
+	}
+}
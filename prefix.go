@@ -0,0 +1,52 @@
+package gomigration
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gocraft/dbr"
+)
+
+// PrefixPlaceholder is replaced by the TablePrefix of the MigrationManager in the statements of sql files.
+const PrefixPlaceholder = "${PREFIX}"
+
+// errNoPrefix is returned when the TablePrefix is asked for in a transaction not begun by the MigrationManager.
+var errNoPrefix = errors.New("the table prefix is only known in transactions begun by the MigrationManager")
+
+// TablePrefix returns the TablePrefix of the MigrationManager that began transaction, so migrations written in Go can
+// serve several prefixed installs. Transactions not begun by the MigrationManager, like the ones passed to
+// MigrationRunnerTx or ApplyUp, do not know the prefix, so it fails instead of silently using unprefixed tables.
+func TablePrefix(transaction *dbr.Tx) (string, error) {
+	if nil == transaction.Session {
+		return "", errNoPrefix
+	}
+	monitor, ok := transaction.EventReceiver.(*txMonitor)
+	if !ok {
+		return "", errNoPrefix
+	}
+	return monitor.prefix, nil
+}
+
+// PrefixedName returns a function putting the TablePrefix of the MigrationManager that began transaction in front of
+// table names, e.g. prefixed("users"). It fails like TablePrefix.
+func PrefixedName(transaction *dbr.Tx) (func(name string) string, error) {
+	prefix, err := TablePrefix(transaction)
+	if nil != err {
+		return nil, err
+	}
+	return func(name string) string {
+		return prefix + name
+	}, nil
+}
+
+// substitutePrefix replaces PrefixPlaceholder in statement, failing like TablePrefix.
+func substitutePrefix(transaction *dbr.Tx, statement string) (string, error) {
+	if !strings.Contains(statement, PrefixPlaceholder) {
+		return statement, nil
+	}
+	prefix, err := TablePrefix(transaction)
+	if nil != err {
+		return "", errors.New(PrefixPlaceholder + " needs a transaction begun by the MigrationManager")
+	}
+	return strings.Replace(statement, PrefixPlaceholder, prefix, -1), nil
+}
@@ -0,0 +1,81 @@
+package gomigration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gocraft/dbr"
+)
+
+func TestWithTablePrefix(t *testing.T) {
+	connection := testConnection(t)
+	mM := NewMigrationManager(connection, WithDialect(SQLite), WithTablePrefix("shop_"))
+	dir := writeFiles(t, map[string]string{
+		"orders.up.sql":   "CREATE TABLE ${PREFIX}orders (id INTEGER);",
+		"orders.down.sql": "DROP TABLE ${PREFIX}orders;",
+	})
+	migrations, err := LoadFromDir(dir)
+	if nil != err {
+		t.Fatal(err)
+	}
+	mM.MigrationRunner(migrations)
+	var tables []string
+	if _, err = connection.NewSession(nil).SelectBySql("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name").LoadValues(&tables); nil != err {
+		t.Fatal(err)
+	}
+	if 2 != len(tables) || "shop_dbMigrations" != tables[0] || "shop_orders" != tables[1] {
+		t.Errorf("expected only prefixed tables, got %v", tables)
+	}
+}
+
+func TestPlanAndTeardownBeginTheirTransactions(t *testing.T) {
+	mM := NewMigrationManager(testConnection(t), WithDialect(SQLite), WithTablePrefix("shop_"))
+	mM.Confirm = func(string) bool { return true }
+	var prefixes []string
+	record := func(transaction *dbr.Tx) error {
+		prefix, err := TablePrefix(transaction)
+		prefixes = append(prefixes, prefix)
+		return err
+	}
+	markExecuted(t, mM, Migration{Name: "applied"})
+	migrations := []Migration{{Name: "applied", Up: noop, Down: record}, {Name: "pending", Up: noop, ShouldRun: func(transaction *dbr.Tx) (bool, error) {
+		return true, record(transaction)
+	}}}
+	session := mM.Connection.NewSession(nil)
+	if _, err := mM.Plan(session, migrations); nil != err {
+		t.Fatal(err)
+	}
+	if err := mM.TeardownAll(session, migrations); nil != err {
+		t.Fatal(err)
+	}
+	if 2 != len(prefixes) || "shop_" != prefixes[0] || "shop_" != prefixes[1] {
+		t.Errorf("expected ShouldRun and Down to see the prefix, got %v", prefixes)
+	}
+}
+
+func TestTablePrefixNeedsTransactionOfManager(t *testing.T) {
+	mM := NewMigrationManager(testConnection(t), WithDialect(SQLite), WithTablePrefix("shop_"))
+	var names []string
+	migration := Migration{Name: "orders", Up: func(transaction *dbr.Tx) error {
+		prefixed, err := PrefixedName(transaction)
+		if nil != err {
+			return err
+		}
+		names = append(names, prefixed("orders"))
+		return nil
+	}}
+	if err := mM.MigrationRunnerContext(context.Background(), []Migration{migration}); nil != err {
+		t.Fatal(err)
+	}
+	if 1 != len(names) || "shop_orders" != names[0] {
+		t.Errorf("expected the prefixed name, got %v", names)
+	}
+	transaction, err := mM.Connection.NewSession(nil).Begin()
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer transaction.Rollback()
+	if err = mM.MigrationRunnerTx(transaction, []Migration{{Name: "other", Up: migration.Up}}); nil == err {
+		t.Error("expected the prefix to be unknown in a transaction not begun by the MigrationManager")
+	}
+}
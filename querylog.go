@@ -0,0 +1,148 @@
+package gomigration
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gocraft/dbr"
+)
+
+// MigrationReceiver provides the dbr.EventReceiver that the transaction of a single migration reports to.
+type MigrationReceiver interface {
+	ForMigration(name string) dbr.EventReceiver
+}
+
+// QueryLogger is a MigrationReceiver writing every query a migration runs to Writer, together with the migration's
+// name. dbr reports the queries of its builders and ExecStatements those of sql file migrations, but statements passed
+// to Exec of the transaction directly are not reported.
+type QueryLogger struct {
+	Writer io.Writer
+}
+
+// NewQueryLogger returns a QueryLogger writing to w.
+func NewQueryLogger(w io.Writer) *QueryLogger {
+	return &QueryLogger{Writer: w}
+}
+
+// ForMigration returns the receiver logging the queries of the migration called name.
+func (l *QueryLogger) ForMigration(name string) dbr.EventReceiver {
+	return &migrationQueryLog{writer: l.Writer, name: name}
+}
+
+type migrationQueryLog struct {
+	dbr.NullEventReceiver
+	writer io.Writer
+	name   string
+}
+
+func (l *migrationQueryLog) EventErrKv(eventName string, err error, kvs map[string]string) error {
+	fmt.Fprintf(l.writer, "[%s] %s: %s %s\n", l.name, eventName, err, kvs["sql"])
+	return err
+}
+
+func (l *migrationQueryLog) TimingKv(eventName string, nanoseconds int64, kvs map[string]string) {
+	if query, ok := kvs["sql"]; ok {
+		fmt.Fprintf(l.writer, "[%s] %s (%s)\n", l.name, query, time.Duration(nanoseconds))
+	}
+}
+
+// SQLAuditLog is a MigrationReceiver writing the statements of every migration to Writer as replayable sql, e.g. into
+// a file kept as change-management record. Each migration starts with a comment naming it and the time it ran, each
+// statement is preceded by a comment with its duration and failed statements are written as comments. It sees the
+// same statements as a QueryLogger. dbr builders put bound values into the reported sql, as do sql files for the
+// FileLoader.Params, which Redact can mask.
+type SQLAuditLog struct {
+	Writer io.Writer
+	// Redact, if set, rewrites every statement before it is written, e.g. to replace sensitive values.
+	Redact func(statement string) string
+}
+
+// NewSQLAuditLog returns a SQLAuditLog writing to w.
+func NewSQLAuditLog(w io.Writer) *SQLAuditLog {
+	return &SQLAuditLog{Writer: w}
+}
+
+// ForMigration writes the comment starting the statements of the migration called name and returns their receiver.
+func (l *SQLAuditLog) ForMigration(name string) dbr.EventReceiver {
+	fmt.Fprintf(l.Writer, "\n-- migration %s at %s\n", name, time.Now().UTC().Format(time.RFC3339))
+	return &migrationAuditLog{log: l}
+}
+
+type migrationAuditLog struct {
+	dbr.NullEventReceiver
+	log *SQLAuditLog
+}
+
+func (l *migrationAuditLog) statement(kvs map[string]string) (string, bool) {
+	statement, ok := kvs["sql"]
+	if ok && nil != l.log.Redact {
+		statement = l.log.Redact(statement)
+	}
+	return statement, ok
+}
+
+func (l *migrationAuditLog) EventErrKv(eventName string, err error, kvs map[string]string) error {
+	if statement, ok := l.statement(kvs); ok {
+		fmt.Fprintf(l.log.Writer, "-- failed: %s\n-- %s;\n", err, strings.Replace(statement, "\n", "\n-- ", -1))
+	}
+	return err
+}
+
+func (l *migrationAuditLog) TimingKv(eventName string, nanoseconds int64, kvs map[string]string) {
+	if statement, ok := l.statement(kvs); ok {
+		fmt.Fprintf(l.log.Writer, "-- took %s\n%s;\n", time.Duration(nanoseconds), statement)
+	}
+}
+
+// RecordStatement reports statement to the receivers of the running migration as if it was executed through
+// ExecStatements, for migrations written in Go that execute sql with Exec of the transaction, which dbr does not
+// report. That way it shows up in a QueryLogger, a SQLAuditLog and the DDL of RunResult.
+func RecordStatement(transaction *dbr.Tx, statement string) {
+	if nil != transaction.Session && nil != transaction.EventReceiver {
+		transaction.TimingKv("gomigration.exec", 0, map[string]string{"sql": statement})
+	}
+}
+
+// ddlRecorder is a MigrationReceiver collecting the DDL statements of every migration while passing all events on to
+// the receiver of next, if there is one.
+type ddlRecorder struct {
+	next       MigrationReceiver
+	statements map[string][]string
+}
+
+func (r *ddlRecorder) ForMigration(name string) dbr.EventReceiver {
+	var receiver dbr.EventReceiver = &dbr.NullEventReceiver{}
+	if nil != r.next {
+		receiver = r.next.ForMigration(name)
+	}
+	r.statements[name] = nil
+	return &migrationDDL{EventReceiver: receiver, recorder: r, name: name}
+}
+
+type migrationDDL struct {
+	dbr.EventReceiver
+	recorder *ddlRecorder
+	name     string
+}
+
+func (m *migrationDDL) TimingKv(eventName string, nanoseconds int64, kvs map[string]string) {
+	if statement, ok := kvs["sql"]; ok && isDDL(statement) {
+		m.recorder.statements[m.name] = append(m.recorder.statements[m.name], statement)
+	}
+	m.EventReceiver.TimingKv(eventName, nanoseconds, kvs)
+}
+
+// isDDL reports if statement changes the schema.
+func isDDL(statement string) bool {
+	fields := strings.Fields(statement)
+	if 0 == len(fields) {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "CREATE", "ALTER", "DROP", "RENAME", "TRUNCATE", "COMMENT":
+		return true
+	}
+	return false
+}
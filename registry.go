@@ -0,0 +1,59 @@
+package gomigration
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Registry collects migrations one by one and rejects duplicate names as soon as they are added:
+//
+//	migrations := NewRegistry().
+//		Add("001_users", createUsers, dropUsers).
+//		AddFromFile("migrations/002_orders.up.sql").
+//		Build()
+type Registry struct {
+	// Loader loads the files passed to AddFromFile.
+	Loader     FileLoader
+	migrations []Migration
+	names      map[string]bool
+}
+
+// NewRegistry returns an empty Registry loading files with the default FileLoader.
+func NewRegistry() *Registry {
+	return &Registry{Loader: NewFileLoader(), names: make(map[string]bool)}
+}
+
+// Add registers a migration and panics if its name was registered before.
+func (r *Registry) Add(name string, up, down Migrate) *Registry {
+	return r.AddMigration(Migration{Name: name, Up: up, Down: down})
+}
+
+// AddMigration registers migration and panics if its name was registered before.
+func (r *Registry) AddMigration(migration Migration) *Registry {
+	if nil == r.names {
+		r.names = make(map[string]bool)
+	}
+	if r.names[migration.Name] {
+		panic(errors.New(fmt.Sprintf("migration \"%s\" is registered at least twice", migration.Name)))
+	}
+	r.names[migration.Name] = true
+	r.migrations = append(r.migrations, migration)
+	return r
+}
+
+// AddFromFile registers the migration loaded by FileLoader.LoadFile and panics if it can not be loaded or its name
+// was registered before.
+func (r *Registry) AddFromFile(upFile string) *Registry {
+	migration, err := r.Loader.LoadFile(upFile)
+	if nil != err {
+		panic(err)
+	}
+	return r.AddMigration(migration)
+}
+
+// Build returns the registered migrations in the order they were added.
+func (r *Registry) Build() []Migration {
+	migrations := make([]Migration, len(r.migrations))
+	copy(migrations, r.migrations)
+	return migrations
+}
@@ -0,0 +1,95 @@
+package gomigration
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocraft/dbr"
+)
+
+// ReorderMeta rewrites the meta table so the executed migrations are recorded in the order of migrations, e.g. after
+// migrations were applied out of order during parallel development, so RollbackN and History follow the intended
+// sequence. The existing ids and execution times are kept as a set but handed out again in the order of migrations,
+// the migrations they replace counting right before them. This rewrites the audit trail of when each migration was
+// applied, which may be unwanted. It only works if exactly the migrations in migrations are executed, each recorded
+// once, and changes everything within one transaction. With KeyUUID only the execution times are rewritten.
+func (mM MigrationManager) ReorderMeta(session *dbr.Session, migrations []Migration) error {
+	var order []string
+	for _, migration := range migrations {
+		for _, name := range migration.Replaces {
+			order = append(order, mM.normalize(name))
+		}
+		order = append(order, mM.normalize(migration.Name))
+	}
+	transaction, err := session.Begin()
+	if nil != err {
+		return err
+	}
+	defer transaction.RollbackUnlessCommitted()
+	rows, err := mM.loadMetaRows(transaction)
+	if nil != err {
+		return err
+	}
+	byName := make(map[string]metaRow, len(rows))
+	for _, row := range rows {
+		if _, ok := byName[row.Name.String]; ok {
+			return errors.New(fmt.Sprintf("migration \"%s\" is recorded more than once, see Repair", row.Name.String))
+		}
+		byName[row.Name.String] = row
+	}
+	var missing []string
+	for _, name := range order {
+		if _, ok := byName[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if 0 < len(missing) || len(order) != len(rows) {
+		return errors.New(fmt.Sprintf("executed migrations do not match the migrations, %d of %d executed, pending: %s",
+			len(rows), len(order), strings.Join(missing, ", ")))
+	}
+	ids := make([]int64, 0, len(rows))
+	oldIDs := make(map[string]int64, len(rows))
+	executions := make([]time.Time, 0, len(rows))
+	for _, row := range rows {
+		execution, err := parseExecution(row.Execution.String)
+		if nil != err {
+			return errors.New(fmt.Sprintf("migration \"%s\" has the invalid execution time \"%s\", see Repair", row.Name.String, row.Execution.String))
+		}
+		executions = append(executions, execution)
+		if KeyUUID != mM.Keys {
+			id, err := strconv.ParseInt(row.ID.String, 10, 64)
+			if nil != err {
+				return err
+			}
+			ids = append(ids, id)
+			oldIDs[row.Name.String] = id
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	sort.Slice(executions, func(i, j int) bool { return executions[i].Before(executions[j]) })
+	if KeyUUID != mM.Keys {
+		// move all ids out of the way first, so no new id collides with one not rewritten yet
+		for _, id := range ids {
+			if _, err = transaction.Update(mM.table()).Set("id", -id).Where("id = ?", id).Exec(); nil != err {
+				return err
+			}
+		}
+	}
+	for i, name := range order {
+		row := byName[name]
+		update := transaction.Update(mM.table()).Set("execution", executions[i].Format(timeFormat))
+		if KeyUUID == mM.Keys {
+			update = update.Where("id = ?", row.ID.String)
+		} else {
+			update = update.Set("id", ids[i]).Where("id = ?", -oldIDs[name])
+		}
+		if _, err = update.Exec(); nil != err {
+			return err
+		}
+	}
+	return transaction.Commit()
+}
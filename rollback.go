@@ -0,0 +1,148 @@
+package gomigration
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gocraft/dbr"
+)
+
+// ErrNotConfirmed is returned by destructive operations that the Confirm of the MigrationManager did not allow.
+var ErrNotConfirmed = errors.New("destructive operation was not confirmed by MigrationManager.Confirm")
+
+// ErrRollbackDisabled is returned by operations running Down in the production Environment, see AllowDown.
+var ErrRollbackDisabled = errors.New("running down migrations is disabled in the production environment")
+
+// ProductionEnvironment is the Environment in which Down is not run unless AllowDown is set.
+const ProductionEnvironment = "production"
+
+// downAllowed returns ErrRollbackDisabled if the Environment forbids running Down.
+func (mM MigrationManager) downAllowed() error {
+	if strings.EqualFold(ProductionEnvironment, mM.Environment) && !mM.AllowDown {
+		return ErrRollbackDisabled
+	}
+	return nil
+}
+
+// confirm asks Confirm if operation may run.
+func (mM MigrationManager) confirm(operation string) error {
+	if nil == mM.Confirm || !mM.Confirm(operation) {
+		return ErrNotConfirmed
+	}
+	return nil
+}
+
+// RollbackN undoes the last n executed migrations in the reverse order they were applied in, using the Down of the
+// migration of the same name. All of them have to be part of migrations, which is checked before anything is undone.
+// Each Down runs in its own transaction unless AllInOneRollback is set. It needs to be allowed by Confirm if the Down
+// of one of the migrations is destructive.
+func (mM MigrationManager) RollbackN(session *dbr.Session, migrations []Migration, n int) error {
+	if err := mM.downAllowed(); nil != err {
+		return err
+	}
+	return mM.rollbackN(session, migrations, n, "RollbackN")
+}
+
+// rollbackN undoes the last n executed migrations, all of them if n is negative. Unless operation is empty, it asks
+// Confirm for operation before running a destructive Down.
+func (mM MigrationManager) rollbackN(session *dbr.Session, migrations []Migration, n int, operation string) error {
+	if mM.AllInOneRollback {
+		return mM.rollbackInOne(session, migrations, n, operation)
+	}
+	undo, err := mM.toUndo(session, migrations, n)
+	if nil != err {
+		return err
+	}
+	if err = mM.confirmUndo(operation, undo); nil != err {
+		return err
+	}
+	for _, migration := range undo {
+		if err = mM.RunSingleMigrationDown(session, migration); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// toUndo returns the last n executed migrations in the order they are rolled back in, all of them if n is negative.
+// Like the runners it reads from s and not from ReadConnection, a lagging replica would make it undo the wrong ones.
+func (mM MigrationManager) toUndo(s selector, migrations []Migration, n int) ([]Migration, error) {
+	executed, err := mM.listExecuted(s)
+	if nil != err {
+		return nil, err
+	}
+	if 0 > n {
+		n = len(executed)
+	}
+	byName := make(map[string]Migration, len(migrations))
+	replaced := make(map[string]bool)
+	for _, migration := range migrations {
+		byName[mM.normalize(migration.Name)] = migration
+		for _, name := range migration.Replaces {
+			replaced[mM.normalize(name)] = true
+		}
+	}
+	var undo []Migration
+	for i := len(executed) - 1; 0 <= i && len(undo) < n; i-- {
+		if replaced[executed[i].Name] {
+			// marked as not executed together with the migration replacing it
+			continue
+		}
+		migration, ok := byName[executed[i].Name]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("can not roll back unknown migration \"%s\"", executed[i].Name))
+		}
+		undo = append(undo, migration)
+	}
+	return undo, nil
+}
+
+// confirmUndo asks Confirm for operation if the Down of one of the migrations in undo is destructive.
+func (mM MigrationManager) confirmUndo(operation string, undo []Migration) error {
+	if "" == operation {
+		return nil
+	}
+	for _, migration := range undo {
+		if IsDestructiveDown(migration) {
+			return mM.confirm(operation)
+		}
+	}
+	return nil
+}
+
+// RollbackAll undoes all executed migrations like RollbackN. It needs to be allowed by Confirm if the Down of one of
+// the migrations is destructive.
+func (mM MigrationManager) RollbackAll(session *dbr.Session, migrations []Migration) error {
+	if err := mM.downAllowed(); nil != err {
+		return err
+	}
+	return mM.rollbackN(session, migrations, -1, "RollbackAll")
+}
+
+// rollbackInOne undoes the last n executed migrations like rollbackN in a single transaction, which they are read in.
+func (mM MigrationManager) rollbackInOne(session *dbr.Session, migrations []Migration, n int, operation string) error {
+	transaction, monitor, err := mM.begin(session, mM.Isolation)
+	if nil != err {
+		return err
+	}
+	undo, err := mM.toUndo(transaction, migrations, n)
+	if nil == err {
+		err = mM.confirmUndo(operation, undo)
+	}
+	if nil != err {
+		transaction.Rollback()
+		return err
+	}
+	for _, migration := range undo {
+		if err = mM.monitored(transaction, monitor, migration, "down", mM.applyDown); nil != err {
+			transaction.Rollback()
+			return err
+		}
+	}
+	if err = transaction.Commit(); nil != err {
+		transaction.Rollback()
+		return err
+	}
+	return nil
+}
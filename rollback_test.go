@@ -0,0 +1,103 @@
+package gomigration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gocraft/dbr"
+)
+
+// confirmAll is a Confirm allowing every operation.
+func confirmAll(string) bool {
+	return true
+}
+
+func TestAllInOneRollbackKeepsAllOnFailure(t *testing.T) {
+	mM := testManager(t)
+	mM.Confirm = confirmAll
+	var undone []string
+	down := func(name string) Migrate {
+		return func(*dbr.Tx) error {
+			undone = append(undone, name)
+			return nil
+		}
+	}
+	migrations := []Migration{
+		{Name: "first", Up: noop, Down: func(*dbr.Tx) error { return errors.New("failed") }},
+		{Name: "second", Up: noop, Down: down("second")},
+		{Name: "third", Up: noop, Down: down("third")},
+	}
+	mM.MigrationRunner(migrations)
+	session := mM.Connection.NewSession(nil)
+	mM.AllInOneRollback = true
+	if err := mM.RollbackAll(session, migrations); nil == err {
+		t.Fatal("expected the failing Down to fail the rollback")
+	}
+	if 2 != len(undone) || "third" != undone[0] || "second" != undone[1] {
+		t.Errorf("expected third and second to be undone first, got %v", undone)
+	}
+	if executed, _ := mM.ListExecuted(session); 3 != len(executed) {
+		t.Errorf("expected all migrations to stay executed, got %v", executed)
+	}
+	mM.AllInOneRollback = false
+	if err := mM.RollbackN(session, migrations, 2); nil != err {
+		t.Fatal(err)
+	}
+	if executed, _ := mM.ListExecuted(session); 1 != len(executed) || "first" != executed[0].Name {
+		t.Errorf("expected only first to stay executed, got %v", executed)
+	}
+}
+
+func TestRollbackIgnoresReadConnection(t *testing.T) {
+	for _, inOne := range []bool{false, true} {
+		mM := testManager(t)
+		mM.Confirm = confirmAll
+		mM.AllInOneRollback = inOne
+		migrations := []Migration{{Name: "first", Up: noop, Down: noop}, {Name: "second", Up: noop, Down: noop}}
+		mM.MigrationRunner(migrations)
+		mM.ReadConnection = NewMigrationManager(testConnection(t), WithDialect(SQLite)).Connection
+		session := mM.Connection.NewSession(nil)
+		if err := mM.RollbackAll(session, migrations); nil != err {
+			t.Fatal(err)
+		}
+		if executed, _ := mM.listExecuted(session); 0 != len(executed) {
+			t.Errorf("AllInOneRollback %v: expected all migrations to be undone, got %v", inOne, executed)
+		}
+	}
+}
+
+func TestRollbackConfirmsOnlyDestructiveDowns(t *testing.T) {
+	mM := testManager(t)
+	dir := writeFiles(t, map[string]string{
+		"001_a.up.sql":   "CREATE TABLE a (id INTEGER);",
+		"001_a.down.sql": "ALTER TABLE a ADD note TEXT;",
+		"002_b.up.sql":   "CREATE TABLE b (id INTEGER);",
+		"002_b.down.sql": "DROP TABLE b;",
+	})
+	migrations, err := LoadFromDir(dir)
+	if nil != err {
+		t.Fatal(err)
+	}
+	mM.MigrationRunner(migrations)
+	session := mM.Connection.NewSession(nil)
+	if err = mM.RollbackN(session, migrations, 1); !errors.Is(err, ErrNotConfirmed) {
+		t.Fatalf("expected dropping b to need confirmation, got %v", err)
+	}
+	var operations []string
+	mM.Confirm = func(operation string) bool {
+		operations = append(operations, operation)
+		return true
+	}
+	if err = mM.RollbackN(session, migrations, 1); nil != err {
+		t.Fatal(err)
+	}
+	if err = mM.RollbackAll(session, migrations); nil != err {
+		t.Fatal(err)
+	}
+	if 1 != len(operations) || "RollbackN" != operations[0] {
+		t.Errorf("expected only dropping b to be confirmed, got %v", operations)
+	}
+	if executed, _ := mM.ListExecuted(session); 0 != len(executed) {
+		t.Errorf("expected all migrations to be undone, got %v", executed)
+	}
+}
@@ -0,0 +1,91 @@
+package gomigration
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gocraft/dbr"
+)
+
+// RunResult summarizes a call of Run, ready to be encoded as JSON for deploy logs.
+type RunResult struct {
+	// Applied are the migrations executed by this run.
+	Applied []string `json:"applied"`
+	// Skipped are the migrations that were executed before.
+	Skipped []string `json:"skipped"`
+	// Pending are the migrations still not executed, because they failed, came after the failed one or were skipped
+	// by ShouldRun with SkipWithoutMarking.
+	Pending []string `json:"pending"`
+	// AwaitingApproval are the pending migrations with RequiresApproval, which have to be applied with RunByName.
+	AwaitingApproval []string `json:"awaiting_approval"`
+	// DDL are the DDL statements each applied migration executed, in order, for generating docs of schema changes.
+	// Statements of sql files and of dbr builders are captured, those passed to Exec of the transaction only if the
+	// migration reports them with RecordStatement. Opaque migrations, like External ones, contribute nothing.
+	DDL map[string][]string `json:"ddl"`
+	// Failed is the name of the migration that failed, if any.
+	Failed string `json:"failed,omitempty"`
+	// Duration is how long the run took in nanoseconds.
+	Duration time.Duration `json:"duration_ns"`
+	// Error is the message of the error returned by Run.
+	Error string `json:"error,omitempty"`
+}
+
+// Run applies all pending migrations like MigrationRunnerContext but returns a summary instead of panicking.
+// Applied migrations are found by comparing the meta table read through session before and after the run, so
+// migrations applied by a concurrent process meanwhile also count as applied.
+func (mM MigrationManager) Run(session *dbr.Session, migrations []Migration) (RunResult, error) {
+	start := time.Now()
+	result := RunResult{Applied: []string{}, Skipped: []string{}, Pending: []string{}, AwaitingApproval: []string{},
+		DDL: make(map[string][]string)}
+	initialized, err := mM.IsInitialized(session)
+	if nil != err {
+		return finishRun(result, start, err)
+	}
+	before := make(map[string]bool)
+	if initialized {
+		if before, err = mM.executedSet(session); nil != err {
+			return finishRun(result, start, err)
+		}
+	}
+	recorder := &ddlRecorder{next: mM.QueryReceiver, statements: make(map[string][]string)}
+	mM.QueryReceiver = recorder
+	runErr := mM.MigrationRunnerContext(context.Background(), migrations)
+	after, err := mM.executedSet(session)
+	if nil != err {
+		if nil == runErr {
+			runErr = err
+		}
+		return finishRun(result, start, runErr)
+	}
+	for _, migration := range migrations {
+		name := mM.normalize(migration.Name)
+		switch {
+		case before[name]:
+			result.Skipped = append(result.Skipped, migration.Name)
+		case after[name]:
+			result.Applied = append(result.Applied, migration.Name)
+			if statements := recorder.statements[migration.Name]; 0 < len(statements) {
+				result.DDL[migration.Name] = statements
+			}
+		case migration.RequiresApproval:
+			result.AwaitingApproval = append(result.AwaitingApproval, migration.Name)
+		default:
+			result.Pending = append(result.Pending, migration.Name)
+		}
+	}
+	var migrationErr *MigrationError
+	if errors.As(runErr, &migrationErr) {
+		result.Failed = migrationErr.Name
+	}
+	return finishRun(result, start, runErr)
+}
+
+// finishRun completes result with the duration since start and err.
+func finishRun(result RunResult, start time.Time, err error) (RunResult, error) {
+	result.Duration = time.Since(start)
+	if nil != err {
+		result.Error = err.Error()
+	}
+	return result, err
+}
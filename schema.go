@@ -0,0 +1,87 @@
+package gomigration
+
+import (
+	"strings"
+)
+
+// SchemaCheck selects how strictly Init verifies the structure of an existing meta table.
+type SchemaCheck int
+
+const (
+	// SchemaCheckColumns requires the columns id, name and execution to exist. It is the default.
+	SchemaCheckColumns SchemaCheck = iota
+	// SchemaCheckTypes additionally requires an integer id, a textual name and a date/time execution column.
+	SchemaCheckTypes
+	// SchemaCheckOff skips the verification.
+	SchemaCheckOff
+)
+
+// SchemaError lists why the meta table does not look like one created by the MigrationManager, which usually means
+// the configured table name collides with an unrelated table.
+type SchemaError struct {
+	Table    string
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return "table \"" + e.Table + "\" is not usable as migration table: " + strings.Join(e.Problems, ", ")
+}
+
+// expectedColumns maps the required columns of the meta table to the parts their database type must contain.
+var expectedColumns = []struct {
+	name  string
+	types []string
+}{
+	{"id", []string{"INT"}},
+	{"name", []string{"CHAR", "TEXT"}},
+	{"execution", []string{"DATE", "TIME"}},
+}
+
+// CheckSchema verifies the columns of the meta table as selected by SchemaCheck and returns a *SchemaError
+// listing all mismatches.
+func (mM MigrationManager) CheckSchema() error {
+	if SchemaCheckOff == mM.SchemaCheck {
+		return nil
+	}
+	rows, err := mM.Connection.Db.Query("SELECT * FROM " + mM.table() + " WHERE 1 = 0")
+	if nil != err {
+		return err
+	}
+	defer rows.Close()
+	columnTypes, err := rows.ColumnTypes()
+	if nil != err {
+		return err
+	}
+	found := make(map[string]string, len(columnTypes))
+	for _, columnType := range columnTypes {
+		found[strings.ToLower(columnType.Name())] = strings.ToUpper(columnType.DatabaseTypeName())
+	}
+	schemaError := &SchemaError{Table: mM.table()}
+	for _, expected := range expectedColumns {
+		databaseType, ok := found[expected.name]
+		if !ok {
+			schemaError.Problems = append(schemaError.Problems, "column "+expected.name+" is missing")
+			continue
+		}
+		types := expected.types
+		if "id" == expected.name && KeyUUID == mM.Keys {
+			types = []string{"CHAR", "UUID"}
+		}
+		if SchemaCheckTypes == mM.SchemaCheck && !containsAny(databaseType, types) {
+			schemaError.Problems = append(schemaError.Problems, "column "+expected.name+" has unexpected type "+databaseType)
+		}
+	}
+	if 0 == len(schemaError.Problems) {
+		return nil
+	}
+	return schemaError
+}
+
+func containsAny(s string, parts []string) bool {
+	for _, part := range parts {
+		if strings.Contains(s, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package gomigration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gocraft/dbr"
+)
+
+// initError returns the error Init panicked with when building a MigrationManager with options on connection.
+func initError(connection *dbr.Connection, options ...Option) (rErr error) {
+	defer func() {
+		if r := recover(); nil != r {
+			rErr = r.(error)
+		}
+	}()
+	NewMigrationManager(connection, options...)
+	return nil
+}
+
+// execute runs statement on connection.
+func execute(t *testing.T, connection *dbr.Connection, statement string) {
+	t.Helper()
+	if _, err := connection.Db.Exec(statement); nil != err {
+		t.Fatal(err)
+	}
+}
+
+func TestInitRejectsUnrelatedTable(t *testing.T) {
+	connection := testConnection(t)
+	execute(t, connection, "CREATE TABLE dbMigrations (title TEXT)")
+	var schemaErr *SchemaError
+	if err := initError(connection, WithDialect(SQLite)); !errors.As(err, &schemaErr) || 3 != len(schemaErr.Problems) {
+		t.Fatalf("expected a *SchemaError with three problems, got %v", err)
+	}
+	if err := initError(connection, WithDialect(SQLite), WithSchemaCheck(SchemaCheckOff)); nil != err {
+		t.Errorf("expected SchemaCheckOff to skip the verification, got %v", err)
+	}
+}
+
+func TestSchemaCheckTypes(t *testing.T) {
+	connection := testConnection(t)
+	execute(t, connection, "CREATE TABLE dbMigrations (id TEXT, name TEXT, execution TEXT)")
+	if err := initError(connection, WithDialect(SQLite)); nil != err {
+		t.Errorf("expected the columns to suffice by default, got %v", err)
+	}
+	var schemaErr *SchemaError
+	if err := initError(connection, WithDialect(SQLite), WithSchemaCheck(SchemaCheckTypes)); !errors.As(err, &schemaErr) || 2 != len(schemaErr.Problems) {
+		t.Errorf("expected a *SchemaError about the types of id and execution, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package gomigration
+
+import (
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gocraft/dbr"
+)
+
+// SchemaDiff holds the statements turning one MySQL schema into another, see DiffSchemas.
+type SchemaDiff struct {
+	// Up turns the current schema into the target schema, Down reverts it.
+	Up, Down []string
+}
+
+// Migration returns a migration called name executing the statements of the diff.
+func (d SchemaDiff) Migration(name string) Migration {
+	return Migration{Name: name, Up: execAll(d.Up), Down: execAll(d.Down)}
+}
+
+// execAll returns a Migrate executing statements one after the other.
+func execAll(statements []string) Migrate {
+	return func(transaction *dbr.Tx) error {
+		for _, statement := range statements {
+			if _, err := transaction.Exec(statement); nil != err {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// schemaColumn is a column as described by information_schema.
+type schemaColumn struct {
+	table, name, columnType, nullable string
+	defaultValue                      sql.NullString
+}
+
+// DiffSchemas compares the MySQL databases of current and target by their information_schema and returns the
+// statements creating and dropping tables and adding and dropping columns so current matches target. Other
+// differences like changed types, indexes or foreign keys are not detected. The result is best-effort and must be
+// reviewed by a human before it is used as a migration, e.g. a renamed column shows up as dropped and added, losing
+// its data. Tables listed in ignore, like the meta tables, are skipped.
+func DiffSchemas(current, target *dbr.Connection, ignore ...string) (SchemaDiff, error) {
+	currentColumns, err := loadColumns(current.Db, ignore)
+	if nil != err {
+		return SchemaDiff{}, err
+	}
+	targetColumns, err := loadColumns(target.Db, ignore)
+	if nil != err {
+		return SchemaDiff{}, err
+	}
+	return diffSchemas(current.Db, target.Db, currentColumns, targetColumns)
+}
+
+// diffSchemas compares the columns loaded from the databases current and target, which are only queried for the
+// statements creating tables missing on the other side.
+func diffSchemas(current, target *sql.DB, currentColumns, targetColumns map[string][]schemaColumn) (SchemaDiff, error) {
+	var diff SchemaDiff
+	// undo holds the statements of Down in the order of the changes of Up they undo
+	var undo [][]string
+	for _, table := range sortedTables(targetColumns) {
+		if _, ok := currentColumns[table]; ok {
+			continue
+		}
+		create, err := showCreateTable(target, table)
+		if nil != err {
+			return diff, err
+		}
+		diff.Up = append(diff.Up, create)
+		undo = append(undo, []string{"DROP TABLE " + quoteIdentifier(MySQL, table)})
+	}
+	for _, table := range sortedTables(currentColumns) {
+		if _, ok := targetColumns[table]; ok {
+			continue
+		}
+		create, err := showCreateTable(current, table)
+		if nil != err {
+			return diff, err
+		}
+		diff.Up = append(diff.Up, "DROP TABLE "+quoteIdentifier(MySQL, table))
+		undo = append(undo, []string{create})
+	}
+	for _, table := range sortedTables(targetColumns) {
+		columns, ok := currentColumns[table]
+		if !ok {
+			continue
+		}
+		up, down := diffColumns(table, columns, targetColumns[table])
+		diff.Up = append(diff.Up, up...)
+		undo = append(undo, down)
+	}
+	// undo the changes in reverse order, while the column changes of a table keep the order diffColumns returned, so
+	// every re-added column is positioned after one that exists at that point
+	for i := len(undo) - 1; 0 <= i; i-- {
+		diff.Down = append(diff.Down, undo[i]...)
+	}
+	return diff, nil
+}
+
+// diffColumns returns the statements adding and dropping columns of table so the columns from match to, and the ones
+// reverting them. Dropped columns are re-added in ascending position, each after the previous column of from, which is
+// either kept or re-added before.
+func diffColumns(table string, from, to []schemaColumn) (up, down []string) {
+	alter := "ALTER TABLE " + quoteIdentifier(MySQL, table)
+	fromNames := make(map[string]schemaColumn, len(from))
+	for _, column := range from {
+		fromNames[column.name] = column
+	}
+	toNames := make(map[string]bool, len(to))
+	for i, column := range to {
+		toNames[column.name] = true
+		if _, ok := fromNames[column.name]; ok {
+			continue
+		}
+		position := " FIRST"
+		if 0 < i {
+			position = " AFTER " + quoteIdentifier(MySQL, to[i-1].name)
+		}
+		up = append(up, alter+" ADD COLUMN "+columnDefinition(column)+position)
+		down = append(down, alter+" DROP COLUMN "+quoteIdentifier(MySQL, column.name))
+	}
+	for i, column := range from {
+		if toNames[column.name] {
+			continue
+		}
+		position := " FIRST"
+		if 0 < i {
+			position = " AFTER " + quoteIdentifier(MySQL, from[i-1].name)
+		}
+		up = append(up, alter+" DROP COLUMN "+quoteIdentifier(MySQL, column.name))
+		down = append(down, alter+" ADD COLUMN "+columnDefinition(column)+position)
+	}
+	return up, down
+}
+
+// columnDefinition returns the definition of column for ADD COLUMN.
+func columnDefinition(column schemaColumn) string {
+	definition := quoteIdentifier(MySQL, column.name) + " " + column.columnType
+	if "NO" == column.nullable {
+		definition += " NOT NULL"
+	} else {
+		definition += " NULL"
+	}
+	if column.defaultValue.Valid {
+		definition += " DEFAULT " + defaultLiteral(column.defaultValue.String)
+	}
+	return definition
+}
+
+// defaultLiteral quotes a default value unless it is a number, NULL or an expression like CURRENT_TIMESTAMP.
+func defaultLiteral(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); nil == err {
+		return value
+	}
+	upper := strings.ToUpper(value)
+	if "NULL" == upper || strings.HasPrefix(upper, "CURRENT_TIMESTAMP") || strings.HasPrefix(value, "(") {
+		return value
+	}
+	return quoteString(MySQL, value)
+}
+
+// loadColumns returns the columns of the tables of the current database of db by table name, in their order.
+func loadColumns(db *sql.DB, ignore []string) (map[string][]schemaColumn, error) {
+	rows, err := db.Query(`SELECT TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT
+FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() ORDER BY TABLE_NAME, ORDINAL_POSITION`)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+	skip := make(map[string]bool, len(ignore))
+	for _, table := range ignore {
+		skip[table] = true
+	}
+	tables := make(map[string][]schemaColumn)
+	for rows.Next() {
+		var column schemaColumn
+		if err = rows.Scan(&column.table, &column.name, &column.columnType, &column.nullable, &column.defaultValue); nil != err {
+			return nil, err
+		}
+		if !skip[column.table] {
+			tables[column.table] = append(tables[column.table], column)
+		}
+	}
+	return tables, rows.Err()
+}
+
+// showCreateTable returns the statement creating table in the current database of db.
+func showCreateTable(db *sql.DB, table string) (string, error) {
+	var name, create string
+	err := db.QueryRow("SHOW CREATE TABLE "+quoteIdentifier(MySQL, table)).Scan(&name, &create)
+	return create, err
+}
+
+func sortedTables(tables map[string][]schemaColumn) []string {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
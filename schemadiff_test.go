@@ -0,0 +1,57 @@
+package gomigration
+
+import (
+	"reflect"
+	"testing"
+)
+
+// columns returns the columns called names of table.
+func columns(table string, names ...string) []schemaColumn {
+	result := make([]schemaColumn, 0, len(names))
+	for _, name := range names {
+		result = append(result, schemaColumn{table: table, name: name, columnType: "int", nullable: "YES"})
+	}
+	return result
+}
+
+func TestDiffSchemasReAddsColumnsInOrder(t *testing.T) {
+	current := map[string][]schemaColumn{"t": columns("t", "a", "b", "c")}
+	target := map[string][]schemaColumn{"t": columns("t", "a")}
+	diff, err := diffSchemas(nil, nil, current, target)
+	if nil != err {
+		t.Fatal(err)
+	}
+	expectedUp := []string{"ALTER TABLE `t` DROP COLUMN `b`", "ALTER TABLE `t` DROP COLUMN `c`"}
+	expectedDown := []string{"ALTER TABLE `t` ADD COLUMN `b` int NULL AFTER `a`", "ALTER TABLE `t` ADD COLUMN `c` int NULL AFTER `b`"}
+	if !reflect.DeepEqual(expectedUp, diff.Up) {
+		t.Errorf("expected Up %v, got %v", expectedUp, diff.Up)
+	}
+	if !reflect.DeepEqual(expectedDown, diff.Down) {
+		t.Errorf("expected Down %v, got %v", expectedDown, diff.Down)
+	}
+}
+
+func TestDiffSchemasAddsAndDropsColumns(t *testing.T) {
+	current := map[string][]schemaColumn{"t": columns("t", "a", "b", "c")}
+	target := map[string][]schemaColumn{"t": columns("t", "x", "a", "c", "y")}
+	diff, err := diffSchemas(nil, nil, current, target)
+	if nil != err {
+		t.Fatal(err)
+	}
+	expectedUp := []string{
+		"ALTER TABLE `t` ADD COLUMN `x` int NULL FIRST",
+		"ALTER TABLE `t` ADD COLUMN `y` int NULL AFTER `c`",
+		"ALTER TABLE `t` DROP COLUMN `b`",
+	}
+	expectedDown := []string{
+		"ALTER TABLE `t` DROP COLUMN `x`",
+		"ALTER TABLE `t` DROP COLUMN `y`",
+		"ALTER TABLE `t` ADD COLUMN `b` int NULL AFTER `a`",
+	}
+	if !reflect.DeepEqual(expectedUp, diff.Up) {
+		t.Errorf("expected Up %v, got %v", expectedUp, diff.Up)
+	}
+	if !reflect.DeepEqual(expectedDown, diff.Down) {
+		t.Errorf("expected Down %v, got %v", expectedDown, diff.Down)
+	}
+}
@@ -0,0 +1,33 @@
+package gomigration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gocraft/dbr"
+)
+
+// defaultSeedTableName is the meta table of NewSeedMigrationManager.
+const defaultSeedTableName = "dbSeeds"
+
+// NewSeedMigrationManager returns a new MigrationManager for data seeds configured by options and initializes it. Seeds
+// are ordinary migrations inserting data, tracked in their own meta table "dbSeeds" next to "dbMigrations" of the
+// schema, so they can be rolled back and applied again, e.g. with RollbackAll, without touching the state of the schema
+// migrations.
+func NewSeedMigrationManager(c *dbr.Connection, options ...Option) MigrationManager {
+	return NewMigrationManagerExplicitTableName(c, defaultSeedTableName, options...)
+}
+
+// RunSchemaAndSeeds applies the pending schema migrations with schema and then the pending seeds with seeds, so seeds
+// always find the complete schema. If a schema migration fails no seed runs, while a failing seed leaves all schema
+// migrations applied. Both managers may share a connection but need different meta tables.
+func RunSchemaAndSeeds(ctx context.Context, schema MigrationManager, schemaMigrations []Migration, seeds MigrationManager, seedMigrations []Migration) error {
+	if schema.table() == seeds.table() {
+		return errors.New(fmt.Sprintf("schema migrations and seeds both use the meta table \"%s\"", schema.table()))
+	}
+	if err := schema.MigrationRunnerContext(ctx, schemaMigrations); nil != err {
+		return err
+	}
+	return seeds.MigrationRunnerContext(ctx, seedMigrations)
+}
@@ -0,0 +1,88 @@
+package gomigration
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Gap is a range of numbers missing from a sequence of migrations, From and To included.
+type Gap struct {
+	From, To int64
+}
+
+func (g Gap) String() string {
+	if g.From == g.To {
+		return strconv.FormatInt(g.From, 10)
+	}
+	return strconv.FormatInt(g.From, 10) + "-" + strconv.FormatInt(g.To, 10)
+}
+
+// SequenceError is the report of CheckSequence.
+type SequenceError struct {
+	Gaps       []Gap
+	Duplicates []int64
+}
+
+func (e *SequenceError) Error() string {
+	problems := make([]string, 0, 2)
+	if 0 < len(e.Gaps) {
+		gaps := make([]string, 0, len(e.Gaps))
+		for _, gap := range e.Gaps {
+			gaps = append(gaps, gap.String())
+		}
+		problems = append(problems, "missing "+strings.Join(gaps, ", "))
+	}
+	if 0 < len(e.Duplicates) {
+		duplicates := make([]string, 0, len(e.Duplicates))
+		for _, duplicate := range e.Duplicates {
+			duplicates = append(duplicates, strconv.FormatInt(duplicate, 10))
+		}
+		problems = append(problems, "duplicated "+strings.Join(duplicates, ", "))
+	}
+	return "migration sequence is broken: " + strings.Join(problems, "; ")
+}
+
+// CheckSequence checks that the numeric prefixes of the migration names, like 3 of "003_users", are consecutive and
+// unique. Migrations without numeric prefix are skipped. It returns a *SequenceError listing the problems or nil.
+func CheckSequence(migrations []Migration) error {
+	seen := make(map[int64]int)
+	numbers := make([]int64, 0, len(migrations))
+	for _, migration := range migrations {
+		number, ok := numericPrefix(migration.Name)
+		if !ok {
+			continue
+		}
+		if 0 == seen[number] {
+			numbers = append(numbers, number)
+		}
+		seen[number]++
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	report := &SequenceError{}
+	for i, number := range numbers {
+		if 0 < i && number > numbers[i-1]+1 {
+			report.Gaps = append(report.Gaps, Gap{From: numbers[i-1] + 1, To: number - 1})
+		}
+		if 1 < seen[number] {
+			report.Duplicates = append(report.Duplicates, number)
+		}
+	}
+	if 0 == len(report.Gaps) && 0 == len(report.Duplicates) {
+		return nil
+	}
+	return report
+}
+
+// numericPrefix returns the number a migration name starts with.
+func numericPrefix(name string) (int64, bool) {
+	end := 0
+	for end < len(name) && '0' <= name[end] && name[end] <= '9' {
+		end++
+	}
+	if 0 == end {
+		return 0, false
+	}
+	number, err := strconv.ParseInt(name[:end], 10, 64)
+	return number, nil == err
+}
@@ -0,0 +1,100 @@
+package gomigration
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gocraft/dbr"
+)
+
+// serverVersions caches the server version per *dbr.Connection.
+var serverVersions sync.Map
+
+// sqlSelector is implemented by both *dbr.Session and *dbr.Tx.
+type sqlSelector interface {
+	SelectBySql(sql string, args ...interface{}) *dbr.SelectBuilder
+}
+
+// ServerVersion returns the version reported by the database server, e.g. "8.0.32" on MySQL. It is only queried once
+// per Connection.
+func (mM MigrationManager) ServerVersion() (string, error) {
+	return mM.serverVersion(mM.Connection.NewSession(nil))
+}
+
+// ServerVersion returns the version of the database server like MigrationManager.ServerVersion.
+func (c MigrationContext) ServerVersion() (string, error) {
+	return c.manager.serverVersion(c.transaction)
+}
+
+func (mM MigrationManager) serverVersion(s sqlSelector) (string, error) {
+	if version, ok := serverVersions.Load(mM.Connection); ok {
+		return version.(string), nil
+	}
+	query := "SELECT VERSION()"
+	switch mM.Dialect {
+	case Postgres:
+		query = "SHOW server_version"
+	case SQLite:
+		query = "SELECT sqlite_version()"
+	}
+	version, err := s.SelectBySql(query).ReturnString()
+	if nil != err {
+		return "", err
+	}
+	serverVersions.Store(mM.Connection, version)
+	return version, nil
+}
+
+// checkServerVersion fails if the server is older than the MinServerVersion of migration.
+func (mM MigrationManager) checkServerVersion(transaction *dbr.Tx, migration Migration) error {
+	version, err := mM.serverVersion(transaction)
+	if nil != err {
+		return migrationError(migration, "up", err)
+	}
+	if compareVersions(version, migration.MinServerVersion) < 0 {
+		return migrationError(migration, "up", errors.New(fmt.Sprintf("requires server version %s but the server runs %s", migration.MinServerVersion, version)))
+	}
+	return nil
+}
+
+// compareVersions compares the leading dotted numbers of two versions like "8.0.32-log" and returns -1, 0 or 1.
+// Missing parts count as zero.
+func compareVersions(a, b string) int {
+	partsA, partsB := versionParts(a), versionParts(b)
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var x, y int
+		if i < len(partsA) {
+			x = partsA[i]
+		}
+		if i < len(partsB) {
+			y = partsB[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts returns the numbers of the leading "1.2.3" part of version.
+func versionParts(version string) []int {
+	end := 0
+	for end < len(version) && ('.' == version[end] || '0' <= version[end] && version[end] <= '9') {
+		end++
+	}
+	var parts []int
+	for _, part := range strings.Split(strings.Trim(version[:end], "."), ".") {
+		number, err := strconv.Atoi(part)
+		if nil != err {
+			break
+		}
+		parts = append(parts, number)
+	}
+	return parts
+}
@@ -0,0 +1,39 @@
+package gomigration
+
+import (
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"8.0.32-log", "8.0", 1},
+		{"5.7.1", "8.0", -1},
+		{"14.5 (Debian 14.5-1)", "14.5", 0},
+		{"3.39.2", "3.40", -1},
+		{"10.4.3-MariaDB", "10.4.3", 0},
+	}
+	for _, test := range tests {
+		if actual := compareVersions(test.a, test.b); test.expected != actual {
+			t.Errorf("%s compared to %s: expected %d, got %d", test.a, test.b, test.expected, actual)
+		}
+	}
+}
+
+func TestMinServerVersion(t *testing.T) {
+	mM := testManager(t)
+	session := mM.Connection.NewSession(nil)
+	future := Migration{Name: "future", Up: noop, MinServerVersion: "999.0"}
+	if err := mM.RunSingleMigrationUp(session, future); nil == err {
+		t.Fatal("expected the migration to require a newer server")
+	}
+	if mM.CheckIfExecuted(session, future) {
+		t.Error("expected the migration not to be marked as executed")
+	}
+	current := Migration{Name: "current", Up: noop, MinServerVersion: "3.0"}
+	if err := mM.RunSingleMigrationUp(session, current); nil != err {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,41 @@
+package gomigration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+// ErrInterrupted is returned by MigrationRunnerContext when its context was done before all migrations were applied.
+var ErrInterrupted = errors.New("migration run was interrupted")
+
+// interrupted returns an error wrapping ErrInterrupted if ctx is done, naming the migration that was not started.
+func interrupted(ctx context.Context, migration Migration) error {
+	if err := ctx.Err(); nil != err {
+		return fmt.Errorf("%w before migration \"%s\": %s", ErrInterrupted, migration.Name, err)
+	}
+	return nil
+}
+
+// InterruptOnSignal returns a context that is cancelled as soon as one of signals, like syscall.SIGTERM, is received,
+// to be passed to MigrationRunnerContext for a graceful shutdown: the running migration is finished, the following
+// ones are not started and ErrInterrupted is returned, so the schema is never left with a migration applied halfway.
+// Call the returned function once the run is over to stop listening for the signals.
+func InterruptOnSignal(ctx context.Context, signals ...os.Signal) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+	go func() {
+		select {
+		case <-received:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(received)
+		cancel()
+	}
+}
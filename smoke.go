@@ -0,0 +1,34 @@
+package gomigration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gocraft/dbr"
+)
+
+// SmokeTest validates migrations on a throwaway database before they reach production, e.g. in CI: it connects to a
+// clone by calling createClone, which may copy the production schema or return an empty database, applies all
+// migrations with the settings of this MigrationManager, rolls back every executed migration and closes the clone.
+// The first error of any step is returned. ReadConnection, LockConnection and Metrics are not used for the clone, so
+// production is never touched, and the clone is never treated as the production Environment.
+func (mM MigrationManager) SmokeTest(createClone func() (*dbr.Connection, error), migrations []Migration) (rErr error) {
+	clone, err := createClone()
+	if nil != err {
+		return fmt.Errorf("creating clone: %w", err)
+	}
+	defer func() {
+		if err := clone.Db.Close(); nil != err && nil == rErr {
+			rErr = err
+		}
+	}()
+	mM.Connection, mM.ReadConnection, mM.LockConnection, mM.Metrics = clone, nil, nil, nil
+	mM.Environment = ""
+	if err = mM.MigrationRunnerContext(context.Background(), migrations); nil != err {
+		return fmt.Errorf("applying migrations: %w", err)
+	}
+	if err = mM.rollbackN(clone.NewSession(nil), migrations, -1, ""); nil != err {
+		return fmt.Errorf("rolling back migrations: %w", err)
+	}
+	return nil
+}
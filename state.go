@@ -0,0 +1,116 @@
+package gomigration
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gocraft/dbr"
+)
+
+// ExportState returns a sql script recreating the rows of the meta table on another database.
+// Every statement only inserts its row if a migration of that name is not recorded yet, so the script may be run
+// repeatedly. The meta table needs to exist on the target, e.g. by calling Init first. Ids are not exported, so the
+// script does not work for targets using KeyUUID.
+func (mM MigrationManager) ExportState(session *dbr.Session) (string, error) {
+	executed, err := mM.ListExecuted(session)
+	if nil != err {
+		return "", err
+	}
+	table := mM.table()
+	var script bytes.Buffer
+	for _, e := range executed {
+		name := quoteString(mM.Dialect, e.Name)
+		execution := e.Execution
+		if t, err := parseExecution(e.Execution); nil == err {
+			execution = t.Format(timeFormat)
+		}
+		script.WriteString("INSERT INTO " + table + " (name, execution) SELECT " + name + ", " +
+			quoteString(mM.Dialect, execution) + " FROM (SELECT 1) AS source WHERE NOT EXISTS (SELECT 1 FROM " + table +
+			" WHERE " + strings.Replace(nameEquals(mM.Dialect), "?", name, 1) + ");\n")
+	}
+	return script.String(), nil
+}
+
+// ImportState records the given rows, e.g. read by ListExecuted from another database, within one transaction and returns
+// how many were added. Names that are recorded already are skipped. Rows without a name, names listed twice and
+// names only differing in case from another imported or recorded name are rejected before anything is written.
+// A missing or unparseable execution time is replaced by the current time.
+func (mM MigrationManager) ImportState(session *dbr.Session, rows []ExecutedMigration) (int, error) {
+	transaction, err := session.Begin()
+	if nil != err {
+		return 0, err
+	}
+	defer transaction.RollbackUnlessCommitted()
+	executed, err := mM.executedSet(transaction)
+	if nil != err {
+		return 0, err
+	}
+	folded := make(map[string]string)
+	for name := range executed {
+		folded[strings.ToLower(name)] = name
+	}
+	imported := make(map[string]bool)
+	for _, row := range rows {
+		if "" == row.Name {
+			return 0, errors.New("imported migrations must have a name")
+		}
+		if imported[row.Name] {
+			return 0, errors.New(fmt.Sprintf("migration \"%s\" is imported at least twice", row.Name))
+		}
+		if other, ok := folded[strings.ToLower(row.Name)]; ok && other != row.Name {
+			return 0, errors.New(fmt.Sprintf("migration \"%s\" only differs in case from \"%s\"", row.Name, other))
+		}
+		imported[row.Name] = true
+		folded[strings.ToLower(row.Name)] = row.Name
+	}
+	added := 0
+	for _, row := range rows {
+		if executed[row.Name] {
+			continue
+		}
+		execution := time.Now()
+		if t, err := parseExecution(row.Execution); nil == err {
+			execution = t
+		}
+		builder := transaction.InsertInto(mM.table()).Pair("name", row.Name).Pair("execution", execution.Format(timeFormat))
+		if KeyUUID == mM.Keys {
+			id, err := newUUID()
+			if nil != err {
+				return 0, err
+			}
+			builder = builder.Pair("id", id)
+		}
+		if "" != mM.PartitionColumn {
+			builder = builder.Pair(mM.PartitionColumn, mM.PartitionValue)
+		}
+		_, err = builder.Exec()
+		if nil != err {
+			return 0, err
+		}
+		added++
+	}
+	if err = transaction.Commit(); nil != err {
+		return 0, err
+	}
+	return added, nil
+}
+
+// quoteString returns value as a string literal of dialect.
+func quoteString(dialect Dialect, value string) string {
+	if MySQL == dialect {
+		value = strings.Replace(value, `\`, `\\`, -1)
+	}
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
+
+// parseExecution parses the execution column, which depending on the driver settings is read in timeFormat or RFC 3339.
+func parseExecution(execution string) (time.Time, error) {
+	t, err := time.Parse(timeFormat, execution)
+	if nil != err {
+		return time.Parse(time.RFC3339Nano, execution)
+	}
+	return t, nil
+}
@@ -0,0 +1,29 @@
+package gomigration
+
+import (
+	"github.com/gocraft/dbr"
+)
+
+// RunStateless applies the Up of every migration, each in its own transaction, without reading or writing the meta
+// table, e.g. for throwaway test databases. It relies entirely on the migrations being idempotent, like
+// CREATE TABLE IF NOT EXISTS, since all of them run again every time. It stops at the first failing migration.
+func (mM MigrationManager) RunStateless(session *dbr.Session, migrations []Migration) error {
+	for _, migration := range migrations {
+		transaction, monitor, err := mM.begin(session, mM.isolation(migration))
+		if nil != err {
+			return err
+		}
+		err = mM.monitored(transaction, monitor, migration, "up", func(transaction *dbr.Tx, migration Migration) error {
+			return callMigrate(migration.Up, transaction, migration, "up")
+		})
+		if nil != err {
+			transaction.Rollback()
+			return err
+		}
+		if err = transaction.Commit(); nil != err {
+			transaction.Rollback()
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package gomigration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gocraft/dbr"
+)
+
+// VersionMode selects how Version identifies the state of a database.
+type VersionMode int
+
+const (
+	// VersionHead uses the name of the migration with the highest id. It is the default.
+	VersionHead VersionMode = iota
+	// VersionHash uses a hex encoded SHA-256 over the sorted names of all applied migrations,
+	// so two databases share a version exactly if they have the same set of migrations applied.
+	VersionHash
+)
+
+// ExecutedMigration is a single row of the meta table.
+type ExecutedMigration struct {
+	// ID is the id of meta tables using KeyAutoIncrement and zero with KeyUUID.
+	ID int64 `db:"id"`
+	// UUID is the id of meta tables using KeyUUID.
+	UUID      string `db:"uuid"`
+	Name      string `db:"name"`
+	Execution string `db:"execution"`
+	// Meta is the JSON encoded Meta of the migration, if it had one.
+	Meta dbr.NullString `db:"meta"`
+	// Checksum is the Checksum of the migration when it was applied, if it had one.
+	Checksum dbr.NullString `db:"checksum"`
+	// Release is the Release of the MigrationManager that applied the migration, if it had one.
+	Release dbr.NullString `db:"version"`
+	// Duplicate is set by ListExecuted for rows whose name is recorded more than once, which should never happen.
+	Duplicate bool `db:"-"`
+}
+
+// Metadata decodes Meta, returning nil for a migration without.
+func (e ExecutedMigration) Metadata() (map[string]string, error) {
+	if !e.Meta.Valid || "" == e.Meta.String {
+		return nil, nil
+	}
+	var meta map[string]string
+	err := json.Unmarshal([]byte(e.Meta.String), &meta)
+	return meta, err
+}
+
+// MigrationStatus tells if a migration was applied.
+type MigrationStatus struct {
+	Name      string
+	Executed  bool
+	Execution string
+	// Irreversible is set for migrations that can not be undone.
+	Irreversible bool
+}
+
+// ListExecuted returns all applied migrations in the order selected by OrderBy, by default by id, which is the order
+// they were applied in. With KeyUUID they are ordered by execution time instead, so the order of migrations applied
+// within the same second is undefined, which also affects rolling back and the VersionHead. Names recorded more than
+// once are flagged as Duplicate.
+func (mM MigrationManager) ListExecuted(session *dbr.Session) ([]ExecutedMigration, error) {
+	return mM.listExecuted(mM.readSession(session))
+}
+
+// listExecuted is ListExecuted reading from s.
+func (mM MigrationManager) listExecuted(s selector) ([]ExecutedMigration, error) {
+	if err := mM.checkOrdering(); nil != err {
+		return nil, err
+	}
+	var executed []ExecutedMigration
+	var err error
+	if KeyUUID == mM.Keys {
+		_, err = mM.ordered(mM.selectExecuted(s, "id AS uuid", "name", "execution", "meta", "checksum", "version")).
+			LoadStructs(&executed)
+	} else {
+		_, err = mM.ordered(mM.selectExecuted(s, "*")).LoadStructs(&executed)
+	}
+	if nil != err {
+		return nil, err
+	}
+	counts := make(map[string]int, len(executed))
+	for _, e := range executed {
+		counts[e.Name]++
+	}
+	for i := range executed {
+		executed[i].Duplicate = 1 < counts[executed[i].Name]
+	}
+	return executed, nil
+}
+
+// Version returns an identifier of the applied migrations as selected by VersionMode, or "" if none were applied.
+func (mM MigrationManager) Version(session *dbr.Session) (string, error) {
+	executed, err := mM.ListExecuted(session)
+	if nil != err || 0 == len(executed) {
+		return "", err
+	}
+	if VersionHash != mM.VersionMode {
+		return executed[len(executed)-1].Name, nil
+	}
+	return stateHash(executed), nil
+}
+
+// StateHash returns a hash of the whole set of applied migrations, which changes exactly when a migration is applied
+// or rolled back, including out of order, e.g. to invalidate caches depending on the schema. It is the hex encoded
+// SHA-256 of the alphabetically sorted names joined by newlines, and that of the empty string without any applied
+// migration. The algorithm is stable, so hashes can be compared across releases.
+func (mM MigrationManager) StateHash(session *dbr.Session) (string, error) {
+	executed, err := mM.ListExecuted(session)
+	if nil != err {
+		return "", err
+	}
+	return stateHash(executed), nil
+}
+
+func stateHash(executed []ExecutedMigration) string {
+	hash := sha256.Sum256([]byte(strings.Join(sortedNames(executed), "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+// ExecutedNames returns the names of all applied migrations sorted alphabetically, a canonical form of the executed
+// set meant for logs and golden files that does not depend on ids or the order of map iteration.
+func (mM MigrationManager) ExecutedNames(session *dbr.Session) ([]string, error) {
+	executed, err := mM.ListExecuted(session)
+	if nil != err {
+		return nil, err
+	}
+	return sortedNames(executed), nil
+}
+
+// sortedNames returns the names of executed sorted alphabetically.
+func sortedNames(executed []ExecutedMigration) []string {
+	names := make([]string, 0, len(executed))
+	for _, e := range executed {
+		names = append(names, e.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AssertUpToDate returns an error naming the pending migrations unless all of them were executed, e.g. for health
+// checks of instances that do not run migrations themselves. Migrations with SkipWithoutMarking are not counted, as
+// they stay pending for as long as their ShouldRun declines, and neither are migrations with RequiresApproval or,
+// with StopAtApproval, the ones after them, which the runners leave pending as well.
+func (mM MigrationManager) AssertUpToDate(session *dbr.Session, migrations []Migration) error {
+	if err := mM.reachable(); nil != err {
+		return err
+	}
+	executed, err := mM.executedSet(mM.readSession(session))
+	if nil != err {
+		return err
+	}
+	var pending []string
+	for _, migration := range migrations {
+		if executed[mM.normalize(migration.Name)] || migration.SkipWithoutMarking {
+			continue
+		}
+		if migration.RequiresApproval {
+			if mM.StopAtApproval {
+				break
+			}
+			continue
+		}
+		pending = append(pending, migration.Name)
+	}
+	if 0 == len(pending) {
+		return nil
+	}
+	return errors.New(fmt.Sprintf("%d migrations are pending: %s", len(pending), strings.Join(pending, ", ")))
+}
+
+const (
+	// waitInterval is the first pause between two checks of WaitUntilUpToDate, it doubles up to maxWaitInterval.
+	waitInterval    = 250 * time.Millisecond
+	maxWaitInterval = 5 * time.Second
+)
+
+// WaitUntilUpToDate blocks until AssertUpToDate succeeds, e.g. so instances that do not migrate themselves wait for
+// the one holding the migration lock. It checks right away and then after pauses starting at 250ms and doubling up to
+// 5s. If the migrations are still not executed once timeout elapsed, the last error of AssertUpToDate is returned.
+func (mM MigrationManager) WaitUntilUpToDate(session *dbr.Session, migrations []Migration, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	interval := waitInterval
+	for {
+		err := mM.AssertUpToDate(session, migrations)
+		if nil == err {
+			return nil
+		}
+		remaining := time.Until(deadline)
+		if 0 >= remaining {
+			return errors.New(fmt.Sprintf("migrations are not up to date after %s: %s", timeout, err))
+		}
+		if interval > remaining {
+			interval = remaining
+		}
+		time.Sleep(interval)
+		if interval *= 2; interval > maxWaitInterval {
+			interval = maxWaitInterval
+		}
+	}
+}
+
+// Status returns the status of every migration in the order of migrations. A migration recorded more than once shows
+// its latest execution.
+func (mM MigrationManager) Status(session *dbr.Session, migrations []Migration) ([]MigrationStatus, error) {
+	executed, err := mM.ListExecuted(session)
+	if nil != err {
+		return nil, err
+	}
+	executions := make(map[string]string, len(executed))
+	for _, e := range executed {
+		executions[e.Name] = e.Execution
+	}
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		execution, ok := executions[mM.normalize(migration.Name)]
+		status = append(status, MigrationStatus{
+			Name:         migration.Name,
+			Executed:     ok,
+			Execution:    execution,
+			Irreversible: migration.Irreversible,
+		})
+	}
+	return status, nil
+}
+
+// Absorbed is a migration covered by another one, see AbsorbedMigrations.
+type Absorbed struct {
+	Name string
+	// By is the name of the executed migration absorbing it.
+	By string
+}
+
+// AbsorbedMigrations returns the migrations listed in Replaces of an executed migration, e.g. the ones folded into a
+// squash, so their code is safe to delete. Only migrations that are recorded, by themselves or by the migration
+// replacing them, are included, as the runners skip these. Migrations without row are never included, not even those
+// listed before an executed Baseline, because the runners would still apply them.
+func (mM MigrationManager) AbsorbedMigrations(session *dbr.Session, migrations []Migration) ([]Absorbed, error) {
+	executed, err := mM.executedSet(mM.readSession(session))
+	if nil != err {
+		return nil, err
+	}
+	replacedBy := make(map[string]string)
+	for _, migration := range migrations {
+		if !executed[mM.normalize(migration.Name)] {
+			continue
+		}
+		for _, replaced := range migration.Replaces {
+			replacedBy[mM.normalize(replaced)] = migration.Name
+		}
+	}
+	var absorbed []Absorbed
+	for _, migration := range migrations {
+		name := mM.normalize(migration.Name)
+		if by, ok := replacedBy[name]; ok && executed[name] {
+			absorbed = append(absorbed, Absorbed{Name: migration.Name, By: by})
+		}
+	}
+	return absorbed, nil
+}
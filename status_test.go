@@ -0,0 +1,87 @@
+package gomigration
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocraft/dbr"
+)
+
+func TestAssertUpToDateIgnoresSkipWithoutMarking(t *testing.T) {
+	mM := testManager(t)
+	skipped := Migration{Name: "conditional", Up: noop, SkipWithoutMarking: true, ShouldRun: func(*dbr.Tx) (bool, error) {
+		return false, nil
+	}}
+	migrations := []Migration{{Name: "first", Up: noop}, skipped}
+	mM.MigrationRunner(migrations)
+	session := mM.Connection.NewSession(nil)
+	if mM.CheckIfExecuted(session, skipped) {
+		t.Fatal("expected the skipped migration to stay pending")
+	}
+	if err := mM.AssertUpToDate(session, migrations); nil != err {
+		t.Errorf("expected to be up to date, got %v", err)
+	}
+	if err := mM.WaitUntilUpToDate(session, migrations, time.Millisecond); nil != err {
+		t.Errorf("expected to be up to date, got %v", err)
+	}
+	if err := mM.AssertUpToDate(session, append(migrations, Migration{Name: "third", Up: noop})); nil == err {
+		t.Error("expected the third migration to be pending")
+	}
+}
+
+func TestAbsorbedMigrations(t *testing.T) {
+	mM := testManager(t)
+	squash := Migration{Name: "squash", Up: noop, Replaces: []string{"first", "second"}}
+	mM.MigrationRunner([]Migration{squash})
+	markExecuted(t, mM, Migration{Name: "baseline", Baseline: true})
+	migrations := []Migration{{Name: "first"}, {Name: "second"}, squash, {Name: "early"}, {Name: "baseline", Baseline: true}, {Name: "pending"}}
+	absorbed, err := mM.AbsorbedMigrations(mM.Connection.NewSession(nil), migrations)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 2 != len(absorbed) || (Absorbed{Name: "first", By: "squash"}) != absorbed[0] || (Absorbed{Name: "second", By: "squash"}) != absorbed[1] {
+		t.Errorf("expected first and second to be absorbed by squash, got %v", absorbed)
+	}
+}
+
+func TestDuplicateRowsAreFlagged(t *testing.T) {
+	mM := testManager(t)
+	var logged bytes.Buffer
+	mM.Logger = log.New(&logged, "", 0)
+	markExecuted(t, mM, Migration{Name: "first"}, Migration{Name: "second"}, Migration{Name: "first"})
+	session := mM.Connection.NewSession(nil)
+	executed, err := mM.ListExecuted(session)
+	if nil != err || 3 != len(executed) {
+		t.Fatalf("expected three rows, got %v, %v", executed, err)
+	}
+	for _, e := range executed {
+		if ("first" == e.Name) != e.Duplicate {
+			t.Errorf("expected only the rows of first to be duplicates, got %+v", e)
+		}
+	}
+	if !mM.CheckIfExecuted(session, Migration{Name: "first"}) || !strings.Contains(logged.String(), "recorded 2 times") {
+		t.Errorf("expected a warning about the duplicate rows, got %q", logged.String())
+	}
+}
+
+func TestAssertUpToDateRespectsApproval(t *testing.T) {
+	mM := testManager(t)
+	migrations := []Migration{{Name: "first", Up: noop}, {Name: "gated", Up: noop, RequiresApproval: true}, {Name: "last", Up: noop}}
+	mM.MigrationRunner(migrations)
+	session := mM.Connection.NewSession(nil)
+	if err := mM.AssertUpToDate(session, migrations); nil != err {
+		t.Errorf("expected the gated migration not to count as pending, got %v", err)
+	}
+	mM.StopAtApproval = true
+	migrations = append(migrations, Migration{Name: "after", Up: noop})
+	mM.MigrationRunner(migrations)
+	if mM.CheckIfExecuted(session, Migration{Name: "after"}) {
+		t.Fatal("expected the runner to stop at the gated migration")
+	}
+	if err := mM.AssertUpToDate(session, migrations); nil != err {
+		t.Errorf("expected the migrations after the gate not to count as pending, got %v", err)
+	}
+}
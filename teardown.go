@@ -0,0 +1,62 @@
+package gomigration
+
+import (
+	"github.com/gocraft/dbr"
+)
+
+// TeardownAll runs the Down of every executed migration in the order of migrations, not in reverse, with foreign key
+// checks disabled and clears the meta table afterwards, all in one transaction. It is meant for tests dropping
+// everything regardless of the order of foreign keys.
+// DANGEROUS: never use it on a production database, rows violating foreign keys are not detected. Checks are disabled
+// with SET FOREIGN_KEY_CHECKS on MySQL and session_replication_role on Postgres, which needs superuser rights. SQLite
+// keeps checking. Checks are enabled again on the connection also when a Down fails. It needs to be allowed by Confirm.
+func (mM MigrationManager) TeardownAll(session *dbr.Session, migrations []Migration) error {
+	if err := mM.downAllowed(); nil != err {
+		return err
+	}
+	if err := mM.confirm("TeardownAll"); nil != err {
+		return err
+	}
+	executed, err := mM.executedSet(session)
+	if nil != err {
+		return err
+	}
+	transaction, _, err := mM.begin(session, mM.Isolation)
+	if nil != err {
+		return err
+	}
+	defer transaction.RollbackUnlessCommitted()
+	if statement := foreignKeyChecksSQL(mM.Dialect, false); "" != statement {
+		if _, err = transaction.Exec(statement); nil != err {
+			return err
+		}
+	}
+	err = mM.teardown(transaction, migrations, executed)
+	if statement := foreignKeyChecksSQL(mM.Dialect, true); "" != statement {
+		if _, enableErr := transaction.Exec(statement); nil != enableErr && nil == err {
+			err = enableErr
+		}
+	}
+	if nil != err {
+		return err
+	}
+	return transaction.Commit()
+}
+
+// teardown runs the Down of the executed migrations and deletes all rows of the meta table within transaction.
+func (mM MigrationManager) teardown(transaction *dbr.Tx, migrations []Migration, executed map[string]bool) error {
+	for _, migration := range migrations {
+		if !executed[mM.normalize(migration.Name)] {
+			continue
+		}
+		if err := callMigrate(migration.Down, transaction, migration, "down"); nil != err {
+			return err
+		}
+	}
+	builder := transaction.DeleteFrom(mM.table())
+	if "" != mM.PartitionColumn {
+		builder = builder.Where(mM.partitionEquals(), mM.PartitionValue)
+	}
+	_, err := builder.Exec()
+	return err
+}
@@ -0,0 +1,49 @@
+package gomigration
+
+import (
+	"context"
+)
+
+// Tracer creates spans around migration runs for distributed tracing, e.g. backed by OpenTelemetry, without this
+// package depending on a tracing library.
+type Tracer interface {
+	// Start begins a span called name with the given attributes as a child of the span of ctx, if any.
+	Start(ctx context.Context, name string, attributes map[string]string) (context.Context, Span)
+}
+
+// Span is a span begun by a Tracer.
+type Span interface {
+	// SetAttribute adds an attribute to the span.
+	SetAttribute(key, value string)
+	// End finishes the span, marking it as failed with err unless err is nil.
+	End(err error)
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(key, value string) {}
+func (nopSpan) End(err error)                  {}
+
+// startSpan begins a span with the Tracer or returns one doing nothing if there is none.
+func (mM MigrationManager) startSpan(ctx context.Context, name string, attributes map[string]string) (context.Context, Span) {
+	if nil == mM.Tracer {
+		return ctx, nopSpan{}
+	}
+	return mM.Tracer.Start(ctx, name, attributes)
+}
+
+// migrationSpan begins the span of applying migration within a run.
+func (mM MigrationManager) migrationSpan(ctx context.Context, migration Migration) Span {
+	_, span := mM.startSpan(ctx, "gomigration.migration", map[string]string{"migration.name": migration.Name, "migration.direction": "up"})
+	return span
+}
+
+// endMigrationSpan tags span with the outcome of applying a migration and ends it.
+func endMigrationSpan(span Span, err error) {
+	outcome := "applied"
+	if nil != err {
+		outcome = "failed"
+	}
+	span.SetAttribute("migration.outcome", outcome)
+	span.End(err)
+}